@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Usage bar settings read from module data
+type usageBar struct {
+	Width      int
+	FilledChar string
+	EmptyChar  string
+	// Colors used below the warning threshold, below the critical threshold and above it
+	Colors [3]string
+	// Color restored after the filled part, which is the value slot by default
+	RestoreColor string
+	// Warning and critical thresholds in percent
+	Thresholds [2]float64
+}
+
+func (sm StormfetchModule) getUsageBar() usageBar {
+	bar := usageBar{
+		Width:        10,
+		FilledChar:   "█",
+		EmptyChar:    "░",
+		Colors:       [3]string{"%4", "%3", "%1"},
+		RestoreColor: "%4",
+		Thresholds:   [2]float64{70, 90},
+	}
+
+	if width, _ := sm.GetData("bar_width", bar.Width); width.(int) > 0 {
+		bar.Width = width.(int)
+	}
+	filledChar, _ := sm.GetData("bar_filled", bar.FilledChar)
+	bar.FilledChar = filledChar.(string)
+	emptyChar, _ := sm.GetData("bar_empty", bar.EmptyChar)
+	bar.EmptyChar = emptyChar.(string)
+
+	colors, _ := sm.GetData("bar_colors", make([]any, 0))
+	for i, color := range colors.([]any) {
+		if i >= len(bar.Colors) {
+			break
+		}
+		bar.Colors[i] = barColorCode(color)
+	}
+	if restoreColor, ok := sm.Data["bar_restore_color"]; ok {
+		bar.RestoreColor = barColorCode(restoreColor)
+	}
+
+	thresholds, _ := sm.GetData("bar_thresholds", make([]any, 0))
+	for i, threshold := range thresholds.([]any) {
+		if i >= len(bar.Thresholds) {
+			break
+		}
+		switch threshold := threshold.(type) {
+		case int:
+			bar.Thresholds[i] = float64(threshold)
+		case float64:
+			bar.Thresholds[i] = threshold
+		}
+	}
+
+	return bar
+}
+
+// Numbers refer to %N color slots, anything else is an inline color spec
+func barColorCode(color any) string {
+	if slot, ok := color.(int); ok && slot >= 0 && slot <= 9 {
+		return "%" + strconv.Itoa(slot)
+	}
+	return "%{" + fmt.Sprint(color) + "}"
+}
+
+// Returns used as a percentage of total
+func usagePercent(used, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Min(math.Max(used/total*100, 0), 100)
+}
+
+func formatPercent(percent float64) string {
+	return strconv.FormatFloat(percent, 'f', 0, 64)
+}
+
+// Renders a bar filled to the given percentage, colored by the threshold it reaches. The restore color is
+// set after the filled part so that the empty part and any text following the bar keep the surrounding color
+func (bar usageBar) Render(percent float64) string {
+	color := bar.Colors[0]
+	if percent >= bar.Thresholds[1] {
+		color = bar.Colors[2]
+	} else if percent >= bar.Thresholds[0] {
+		color = bar.Colors[1]
+	}
+
+	filled := int(math.Round(percent / 100 * float64(bar.Width)))
+	return color + strings.Repeat(bar.FilledChar, filled) + bar.RestoreColor + strings.Repeat(bar.EmptyChar, bar.Width-filled)
+}
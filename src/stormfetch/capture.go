@@ -0,0 +1,441 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Location of recorded command outputs, filesystem statistics and session details inside a system capture
+const capturedCommandsFile = "/.stormfetch/commands.json"
+const capturedStatfsFile = "/.stormfetch/statfs.json"
+const capturedSessionFile = "/.stormfetch/session.json"
+
+// Files read by external libraries that aren't routed through SystemFS
+var extraCapturedFiles = []string{
+	"/proc/cpuinfo",
+	"/usr/share/hwdata/pci.ids",
+	"/usr/share/misc/pci.ids",
+	"/usr/share/pci.ids",
+}
+
+// PCI device attributes read by ghw and the GPU detector
+var capturedPCIAttributes = []string{
+	"vendor", "device", "class", "revision", "subsystem_vendor", "subsystem_device", "modalias", "numa_node",
+	"mem_info_vram_total", "mem_info_vram_used",
+}
+
+// Filesystem statistics used to calculate partition sizes
+type capturedStatfs struct {
+	Type   int64  `json:"type"`
+	Bsize  int64  `json:"bsize"`
+	Blocks uint64 `json:"blocks"`
+	Bfree  uint64 `json:"bfree"`
+	Bavail uint64 `json:"bavail"`
+}
+
+// Process and user session details detectors read from outside the filesystem
+type capturedSession struct {
+	Uid            int      `json:"uid"`
+	InitExecutable string   `json:"init_executable"`
+	Executables    []string `json:"executables"`
+	SessionType    string   `json:"xdg_session_type"`
+}
+
+type capturedFile struct {
+	Type     byte
+	Mode     int64
+	Content  []byte
+	Linkname string
+}
+
+// Filesystem that records every file detectors access
+type capturingFilesystem struct {
+	rootFilesystem
+	mutex  *sync.Mutex
+	files  map[string]capturedFile
+	statfs map[string]capturedStatfs
+}
+
+var capturedCommands map[string]string
+var capturedCommandsMutex sync.Mutex
+var replayedCommands map[string]string
+var replayedStatfs map[string]capturedStatfs
+var replayedSession *capturedSession
+
+func (cfs capturingFilesystem) record(name string, file capturedFile) {
+	cfs.mutex.Lock()
+	defer cfs.mutex.Unlock()
+
+	name = path.Clean("/" + name)
+
+	// Never replace recorded content with a placeholder
+	if existing, ok := cfs.files[name]; ok && existing.Type == file.Type && file.Content == nil {
+		return
+	}
+	cfs.files[name] = file
+}
+
+// Returns a copy of the recorded files
+func (cfs capturingFilesystem) snapshot() map[string]capturedFile {
+	cfs.mutex.Lock()
+	defer cfs.mutex.Unlock()
+
+	files := make(map[string]capturedFile, len(cfs.files))
+	for name, file := range cfs.files {
+		files[name] = file
+	}
+	return files
+}
+
+// Resolves symlinks in every path component and returns the physical path. Each symlink on the way is recorded
+// so that files are stored at their physical path and stay reachable through the symlinks in the capture
+func (cfs capturingFilesystem) resolve(name string) string {
+	resolved := "/"
+	components := strings.Split(path.Clean("/"+name), "/")
+	for links := 0; len(components) > 0; {
+		component := components[0]
+		components = components[1:]
+		if component == "" || component == "." {
+			continue
+		}
+
+		next := path.Join(resolved, component)
+		info, err := os.Lstat(cfs.realPath(next))
+		if err != nil || info.Mode()&os.ModeSymlink == 0 || links >= 40 {
+			resolved = next
+			continue
+		}
+		target, err := os.Readlink(cfs.realPath(next))
+		if err != nil {
+			resolved = next
+			continue
+		}
+		cfs.record(next, capturedFile{Type: tar.TypeSymlink, Mode: 0777, Linkname: target})
+		links++
+
+		// Continue resolving from the symlink target
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		components = append(strings.Split(target, "/"), components...)
+	}
+
+	return resolved
+}
+
+func (cfs capturingFilesystem) recordPlaceholder(name string) {
+	info, err := cfs.rootFilesystem.Stat(name)
+	if err != nil {
+		return
+	}
+
+	if info.IsDir() {
+		cfs.record(name, capturedFile{Type: tar.TypeDir, Mode: 0755})
+	} else {
+		cfs.record(name, capturedFile{Type: tar.TypeReg, Mode: int64(info.Mode().Perm())})
+	}
+}
+
+func (cfs capturingFilesystem) ReadFile(name string) ([]byte, error) {
+	content, err := cfs.rootFilesystem.ReadFile(name)
+	if err == nil {
+		cfs.record(cfs.resolve(name), capturedFile{Type: tar.TypeReg, Mode: 0644, Content: content})
+	}
+	return content, err
+}
+
+func (cfs capturingFilesystem) ReadDir(name string) ([]os.DirEntry, error) {
+	entries, err := cfs.rootFilesystem.ReadDir(name)
+	if err == nil {
+		name = cfs.resolve(name)
+		cfs.record(name, capturedFile{Type: tar.TypeDir, Mode: 0755})
+		for _, entry := range entries {
+			entryPath := path.Join(name, entry.Name())
+			if entry.Type()&os.ModeSymlink != 0 {
+				if target, err := os.Readlink(cfs.realPath(entryPath)); err == nil {
+					cfs.record(entryPath, capturedFile{Type: tar.TypeSymlink, Mode: 0777, Linkname: target})
+				}
+			} else if entry.IsDir() {
+				cfs.record(entryPath, capturedFile{Type: tar.TypeDir, Mode: 0755})
+			} else {
+				cfs.record(entryPath, capturedFile{Type: tar.TypeReg, Mode: 0644})
+			}
+		}
+	}
+	return entries, err
+}
+
+func (cfs capturingFilesystem) Stat(name string) (os.FileInfo, error) {
+	info, err := cfs.rootFilesystem.Stat(name)
+	if err == nil {
+		cfs.recordPlaceholder(cfs.resolve(name))
+	}
+	return info, err
+}
+
+func (cfs capturingFilesystem) EvalSymlinks(name string) (string, error) {
+	resolved, err := cfs.rootFilesystem.EvalSymlinks(name)
+	if err == nil {
+		cfs.recordPlaceholder(cfs.resolve(name))
+	}
+	return resolved, err
+}
+
+func (cfs capturingFilesystem) LookPath(file string) (string, error) {
+	executable, err := cfs.rootFilesystem.LookPath(file)
+	if err == nil {
+		cfs.record(executable, capturedFile{Type: tar.TypeReg, Mode: 0755})
+	}
+	return executable, err
+}
+
+func (cfs capturingFilesystem) Statfs(name string, buf *syscall.Statfs_t) error {
+	err := cfs.rootFilesystem.Statfs(name, buf)
+	if err == nil {
+		cfs.mutex.Lock()
+		cfs.statfs[path.Clean("/"+name)] = capturedStatfs{Type: int64(buf.Type), Bsize: int64(buf.Bsize), Blocks: buf.Blocks, Bfree: buf.Bfree, Bavail: buf.Bavail}
+		cfs.mutex.Unlock()
+	}
+	return err
+}
+
+// Records the PCI devices ghw enumerates for GPU detection
+func (cfs capturingFilesystem) capturePCIDevices() {
+	devices := make([]string, 0)
+	if entries, err := cfs.ReadDir("/sys/bus/pci/devices"); err == nil {
+		for _, entry := range entries {
+			devices = append(devices, path.Join("/sys/bus/pci/devices", entry.Name()))
+		}
+	}
+	if entries, err := cfs.ReadDir("/sys/class/drm"); err == nil {
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), "card") && !strings.Contains(entry.Name(), "-") {
+				devices = append(devices, path.Join("/sys/class/drm", entry.Name(), "device"))
+			}
+		}
+	}
+
+	for _, device := range devices {
+		for _, attribute := range capturedPCIAttributes {
+			cfs.ReadFile(path.Join(device, attribute))
+		}
+		cfs.resolve(path.Join(device, "driver"))
+	}
+}
+
+func recordCommandOutput(command, output string) {
+	capturedCommandsMutex.Lock()
+	defer capturedCommandsMutex.Unlock()
+
+	if capturedCommands != nil {
+		capturedCommands[command] = output
+	}
+}
+
+// Loads recorded command outputs, filesystem statistics and session details from a system capture extracted into
+// the sysroot
+func loadReplayedCommands() {
+	if bytes, err := SystemFS.ReadFile(capturedCommandsFile); err == nil {
+		replayedCommands = make(map[string]string)
+		if err := json.Unmarshal(bytes, &replayedCommands); err != nil {
+			log.Fatalf("Could not read captured commands: %s", err)
+		}
+	}
+
+	if bytes, err := SystemFS.ReadFile(capturedStatfsFile); err == nil {
+		replayedStatfs = make(map[string]capturedStatfs)
+		if err := json.Unmarshal(bytes, &replayedStatfs); err != nil {
+			log.Fatalf("Could not read captured filesystem statistics: %s", err)
+		}
+	}
+
+	if bytes, err := SystemFS.ReadFile(capturedSessionFile); err == nil {
+		replayedSession = &capturedSession{}
+		if err := json.Unmarshal(bytes, replayedSession); err != nil {
+			log.Fatalf("Could not read captured session details: %s", err)
+		}
+	}
+}
+
+// Removes user identifying information from captured content
+func redactCapturedText(name, text string) string {
+	currentUser, err := user.Current()
+	if err != nil {
+		return text
+	}
+
+	// Only keep the current user's passwd entry
+	if name == "/etc/passwd" {
+		for line := range strings.SplitSeq(text, "\n") {
+			userInfo := strings.Split(line, ":")
+			if len(userInfo) == 7 && userInfo[2] == currentUser.Uid {
+				return strings.Join([]string{"user", "x", userInfo[2], userInfo[3], "", "/home/user", userInfo[6]}, ":") + "\n"
+			}
+		}
+		return ""
+	}
+
+	// Only keep the container variable from PID 1's environment
+	if name == "/proc/1/environ" {
+		for variable := range strings.SplitSeq(text, "\x00") {
+			if strings.HasPrefix(variable, "container=") {
+				return variable + "\x00"
+			}
+		}
+		return ""
+	}
+
+	// Hide home directory paths, which usually contain the username
+	if currentUser.HomeDir != "" && currentUser.HomeDir != "/" {
+		text = strings.ReplaceAll(text, currentUser.HomeDir, "/home/user")
+	}
+
+	return text
+}
+
+func captureSystem(archivePath string) {
+	root, ok := SystemFS.(rootFilesystem)
+	if !ok {
+		log.Fatal("Cannot capture system using the current filesystem")
+	}
+
+	// Record all files and commands accessed by detectors
+	cfs := capturingFilesystem{rootFilesystem: root, mutex: &sync.Mutex{}, files: make(map[string]capturedFile), statfs: make(map[string]capturedStatfs)}
+	SystemFS = cfs
+	capturedCommands = make(map[string]string)
+
+	// Execute every module except for user-defined commands. Modules don't time out so that none keep recording in the background
+	moduleConfigs := make([]stormfetchModuleConfig, 0)
+	for _, moduleConfig := range config.Modules {
+		if moduleConfig.Name != "custom" {
+			moduleConfig.Timeout = -1
+			moduleConfigs = append(moduleConfigs, moduleConfig)
+		}
+	}
+	for name := range Modules {
+		if name != "custom" && !slices.ContainsFunc(moduleConfigs, func(mc stormfetchModuleConfig) bool { return mc.Name == name }) {
+			moduleConfigs = append(moduleConfigs, stormfetchModuleConfig{Name: name, Timeout: -1})
+		}
+	}
+	executeModules(moduleConfigs, OutputFormatText)
+
+	for _, file := range extraCapturedFiles {
+		cfs.ReadFile(file)
+	}
+	cfs.capturePCIDevices()
+
+	// Store command outputs
+	capturedCommandsMutex.Lock()
+	for command, output := range capturedCommands {
+		capturedCommands[command] = redactCapturedText("", output)
+	}
+	commandsJson, err := json.MarshalIndent(capturedCommands, "", "  ")
+	capturedCommandsMutex.Unlock()
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfs.record(capturedCommandsFile, capturedFile{Type: tar.TypeReg, Mode: 0644, Content: commandsJson})
+
+	// Store filesystem statistics
+	cfs.mutex.Lock()
+	statfsJson, err := json.MarshalIndent(cfs.statfs, "", "  ")
+	cfs.mutex.Unlock()
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfs.record(capturedStatfsFile, capturedFile{Type: tar.TypeReg, Mode: 0644, Content: statfsJson})
+
+	// Store session details, which are matched against the redacted passwd entry and process list on replay
+	session := capturedSession{Uid: getUid(), InitExecutable: getInitExecutable(), SessionType: getSessionType()}
+	session.Executables, _ = getProcessExecutables()
+	slices.Sort(session.Executables)
+	sessionJson, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfs.record(capturedSessionFile, capturedFile{Type: tar.TypeReg, Mode: 0644, Content: sessionJson})
+
+	if err := writeCaptureArchive(archivePath, cfs.snapshot()); err != nil {
+		log.Fatalf("Could not write system capture: %s", err)
+	}
+
+	fmt.Printf("System capture written to %s\n", archivePath)
+}
+
+func writeCaptureArchive(archivePath string, files map[string]capturedFile) error {
+	// Add parent directories of all files
+	for name := range files {
+		for dir := path.Dir(name); dir != "/"; dir = path.Dir(dir) {
+			if _, ok := files[dir]; !ok {
+				files[dir] = capturedFile{Type: tar.TypeDir, Mode: 0755}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archiveFile.Close()
+
+	gzipWriter := gzip.NewWriter(archiveFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	modTime := time.Now()
+	for _, name := range names {
+		file := files[name]
+		if file.Type == tar.TypeReg && file.Content != nil && name != capturedCommandsFile && name != capturedStatfsFile && name != capturedSessionFile {
+			file.Content = []byte(redactCapturedText(name, string(file.Content)))
+		}
+
+		header := &tar.Header{
+			Typeflag: file.Type,
+			Name:     strings.TrimPrefix(name, "/"),
+			Linkname: file.Linkname,
+			Mode:     file.Mode,
+			Size:     int64(len(file.Content)),
+			ModTime:  modTime,
+		}
+		if file.Type == tar.TypeDir {
+			header.Name += "/"
+		}
+		if file.Type != tar.TypeReg {
+			header.Size = 0
+		}
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if file.Type == tar.TypeReg {
+			if _, err := tarWriter.Write(file.Content); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+
+	return archiveFile.Close()
+}
@@ -2,27 +2,293 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
+const (
+	ColorModeAuto   = "auto"
+	ColorModeAlways = "always"
+	ColorModeNever  = "never"
+)
+
+var ColorModes = []string{ColorModeAuto, ColorModeAlways, ColorModeNever}
+
+// Whether escape codes should be written to the output
+var ColorsEnabled = true
+
+const (
+	ColorDepth16   = 16
+	ColorDepth256  = 256
+	ColorDepthTrue = 1 << 24
+)
+
+// Matches %N color slots and inline %{spec} colors
+var colorCodeRegex = regexp.MustCompile(`%(\d|\{[^}\n]*\})`)
+
+var namedColors = map[string]int{
+	"black":          0,
+	"red":            1,
+	"green":          2,
+	"yellow":         3,
+	"blue":           4,
+	"magenta":        5,
+	"cyan":           6,
+	"white":          7,
+	"bright_black":   8,
+	"gray":           8,
+	"grey":           8,
+	"bright_red":     9,
+	"bright_green":   10,
+	"bright_yellow":  11,
+	"bright_blue":    12,
+	"bright_magenta": 13,
+	"bright_cyan":    14,
+	"bright_white":   15,
+}
+
+var colorAttributes = map[string]int{
+	"bold":      1,
+	"dim":       2,
+	"italic":    3,
+	"underline": 4,
+	"blink":     5,
+	"reverse":   7,
+}
+
+// RGB values of the 16 standard colors (xterm defaults)
+var standardColors = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0}, {0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0}, {92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// Decides whether to output colors based on the color mode, NO_COLOR and whether stdout is a terminal
+func resolveColorMode(colorMode string) bool {
+	switch colorMode {
+	case ColorModeAlways:
+		return true
+	case ColorModeNever:
+		return false
+	default:
+		return os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout.Fd())
+	}
+}
+
+// Detects the amount of colors supported by the terminal
+func getColorDepth() int {
+	colorterm := strings.ToLower(os.Getenv("COLORTERM"))
+	term := os.Getenv("TERM")
+
+	switch {
+	case colorterm == "truecolor" || colorterm == "24bit":
+		return ColorDepthTrue
+	case strings.Contains(term, "256color"):
+		return ColorDepth256
+	case term == "linux" || term == "vt100" || term == "vt220" || term == "ansi":
+		return ColorDepth16
+	default:
+		return ColorDepth256
+	}
+}
+
+// Converts a color specification into an escape sequence. Specifications consist of a color and attributes separated by '+'
+// Colors may be 256-color numbers, hex #rrggbb values or named colors
+func parseColorSpec(spec string, colorDepth int) (string, error) {
+	codes := []string{"0"}
+
+	for _, part := range strings.Split(spec, "+") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" || part == "reset" || part == "default" {
+			continue
+		}
+
+		if attribute, ok := colorAttributes[part]; ok {
+			codes = append(codes, strconv.Itoa(attribute))
+		} else if index, ok := namedColors[part]; ok {
+			codes = append(codes, indexedColorCode(index, colorDepth))
+		} else if strings.HasPrefix(part, "#") && len(part) == 7 {
+			rgb, err := strconv.ParseUint(part[1:], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid hex color: %s", part)
+			}
+			codes = append(codes, rgbColorCode(int(rgb>>16), int(rgb>>8&0xff), int(rgb&0xff), colorDepth))
+		} else if index, err := strconv.Atoi(part); err == nil && index >= 0 && index <= 255 {
+			codes = append(codes, indexedColorCode(index, colorDepth))
+		} else {
+			return "", fmt.Errorf("invalid color: %s", part)
+		}
+	}
+
+	if !ColorsEnabled {
+		return "", nil
+	}
+
+	return "\033[" + strings.Join(codes, ";") + "m", nil
+}
+
+func indexedColorCode(index, colorDepth int) string {
+	if colorDepth == ColorDepth16 {
+		if index >= 16 {
+			r, g, b := color256ToRGB(index)
+			index = nearestStandardColor(r, g, b)
+		}
+		if index < 8 {
+			return strconv.Itoa(30 + index)
+		}
+		return strconv.Itoa(90 + index - 8)
+	}
+
+	return "38;5;" + strconv.Itoa(index)
+}
+
+func rgbColorCode(r, g, b, colorDepth int) string {
+	switch colorDepth {
+	case ColorDepthTrue:
+		return fmt.Sprintf("38;2;%d;%d;%d", r, g, b)
+	case ColorDepth16:
+		return indexedColorCode(nearestStandardColor(r, g, b), colorDepth)
+	default:
+		return "38;5;" + strconv.Itoa(nearestColor256(r, g, b))
+	}
+}
+
+// Returns the RGB value of a color in the 256-color palette
+func color256ToRGB(index int) (int, int, int) {
+	switch {
+	case index < 16:
+		return standardColors[index][0], standardColors[index][1], standardColors[index][2]
+	case index < 232:
+		levels := []int{0, 95, 135, 175, 215, 255}
+		index -= 16
+		return levels[index/36], levels[index/6%6], levels[index%6]
+	default:
+		gray := 8 + (index-232)*10
+		return gray, gray, gray
+	}
+}
+
+func nearestColor256(r, g, b int) int {
+	best, bestDistance := 16, -1
+	for index := 16; index < 256; index++ {
+		cr, cg, cb := color256ToRGB(index)
+		distance := (r-cr)*(r-cr) + (g-cg)*(g-cg) + (b-cb)*(b-cb)
+		if bestDistance == -1 || distance < bestDistance {
+			best, bestDistance = index, distance
+		}
+	}
+	return best
+}
+
+func nearestStandardColor(r, g, b int) int {
+	best, bestDistance := 0, -1
+	for index, color := range standardColors {
+		distance := (r-color[0])*(r-color[0]) + (g-color[1])*(g-color[1]) + (b-color[2])*(b-color[2])
+		if bestDistance == -1 || distance < bestDistance {
+			best, bestDistance = index, distance
+		}
+	}
+	return best
+}
+
 func setupColorMap(asciiArtHeader string) []string {
 	colorMap := make([]string, 10)
+	colorDepth := getColorDepth()
 
 	// Set default color map values
 	for i := range 10 {
 		colorMap[i] = "\033[0m"
+		if !ColorsEnabled {
+			colorMap[i] = ""
+		}
 	}
 
-	// Return if header is empty
-	if asciiArtHeader == "" {
-		return colorMap
+	// Read colors from ascii art header
+	headerColors := 0
+	if asciiArtHeader != "" {
+		ansiColors := strings.Split(strings.TrimPrefix(asciiArtHeader, "#/"), ";")
+		for i := 0; i < 9 && i < len(ansiColors); i++ {
+			if color, err := parseColorSpec(ansiColors[i], colorDepth); err == nil {
+				colorMap[i+1] = color
+			}
+		}
+		headerColors = min(len(ansiColors), 9)
+	} else if distroColor := parseAnsiColor(GetDistroInfo().AnsiColor, colorDepth); distroColor != "" {
+		// Use the distro color from os-release as the accent color, with white values like the bundled ascii art
+		colorMap[3] = distroColor
+		colorMap[4], _ = parseColorSpec("15", colorDepth)
 	}
 
-	// Read colors from ascii art header
-	ansiColors := strings.Split(strings.TrimPrefix(asciiArtHeader, "#/"), ";")
-	for i := 0; i < 9 && i < len(ansiColors); i++ {
-		colorMap[i+1] = fmt.Sprintf("\033[38;5;%sm", ansiColors[i])
+	// Apply colors from config to slots not set by the header, or to all slots if forced
+	for i := 0; i < 9 && i < len(config.AnsiiColors); i++ {
+		if i < headerColors && !config.ForceConfigAnsii {
+			continue
+		}
+		if color, err := parseColorSpec(config.AnsiiColors[i], colorDepth); err == nil {
+			colorMap[i+1] = color
+		}
 	}
 
 	return colorMap
 }
+
+// Converts an os-release ANSI_COLOR value to an escape sequence for the given color depth
+func parseAnsiColor(ansiColor string, colorDepth int) string {
+	if ansiColor == "" || !ColorsEnabled {
+		return ""
+	}
+
+	params := strings.Split(ansiColor, ";")
+	codes := make([]string, 0, len(params))
+	for i := 0; i < len(params); i++ {
+		param, err := strconv.Atoi(params[i])
+		if err != nil {
+			return ""
+		}
+
+		// Downgrade extended foreground colors to the supported depth
+		if param == 38 && i+2 < len(params) && params[i+1] == "5" {
+			index, err := strconv.Atoi(params[i+2])
+			if err != nil {
+				return ""
+			}
+			codes = append(codes, indexedColorCode(index, colorDepth))
+			i += 2
+		} else if param == 38 && i+4 < len(params) && params[i+1] == "2" {
+			r, errR := strconv.Atoi(params[i+2])
+			g, errG := strconv.Atoi(params[i+3])
+			b, errB := strconv.Atoi(params[i+4])
+			if errR != nil || errG != nil || errB != nil {
+				return ""
+			}
+			codes = append(codes, rgbColorCode(r, g, b, colorDepth))
+			i += 4
+		} else {
+			codes = append(codes, params[i])
+		}
+	}
+
+	return "\033[0;" + strings.Join(codes, ";") + "m"
+}
+
+// Replaces %N color slots and inline %{spec} colors with escape sequences
+func replaceColorCodes(text string, colorMap []string) string {
+	colorDepth := getColorDepth()
+	return colorCodeRegex.ReplaceAllStringFunc(text, func(code string) string {
+		if code[1] != '{' {
+			return colorMap[code[1]-'0']
+		}
+
+		color, err := parseColorSpec(code[2:len(code)-1], colorDepth)
+		if err != nil {
+			return ""
+		}
+		return color
+	})
+}
+
+// Removes %N color slots and inline %{spec} colors
+func removeColorCodes(text string) string {
+	return colorCodeRegex.ReplaceAllString(text, "")
+}
@@ -0,0 +1,105 @@
+package main
+
+import "testing"
+
+func TestParseColorSpec(t *testing.T) {
+	tests := []struct {
+		spec       string
+		colorDepth int
+		expected   string
+	}{
+		// 24-bit colors
+		{"#ff0000", ColorDepthTrue, "\033[0;38;2;255;0;0m"},
+		{"#123456", ColorDepthTrue, "\033[0;38;2;18;52;86m"},
+		{"196", ColorDepthTrue, "\033[0;38;5;196m"},
+		// Downgraded to the 256-color palette
+		{"#ff0000", ColorDepth256, "\033[0;38;5;196m"},
+		{"#123456", ColorDepth256, "\033[0;38;5;23m"},
+		{"#808080", ColorDepth256, "\033[0;38;5;244m"},
+		{"red", ColorDepth256, "\033[0;38;5;1m"},
+		// Downgraded to the 16 standard colors
+		{"#ff0000", ColorDepth16, "\033[0;91m"},
+		{"#000080", ColorDepth16, "\033[0;34m"},
+		{"196", ColorDepth16, "\033[0;91m"},
+		{"232", ColorDepth16, "\033[0;30m"},
+		{"244", ColorDepth16, "\033[0;90m"},
+		{"red", ColorDepth16, "\033[0;31m"},
+		{"bright_red", ColorDepth16, "\033[0;91m"},
+		{"grey", ColorDepth16, "\033[0;90m"},
+		// Attributes and resets
+		{"bold", ColorDepth256, "\033[0;1m"},
+		{"bold+red", ColorDepth256, "\033[0;1;38;5;1m"},
+		{"Red + Underline", ColorDepth16, "\033[0;31;4m"},
+		{"italic+#00ff00", ColorDepthTrue, "\033[0;3;38;2;0;255;0m"},
+		{"reset", ColorDepth256, "\033[0m"},
+		{"", ColorDepth256, "\033[0m"},
+	}
+
+	for _, test := range tests {
+		color, err := parseColorSpec(test.spec, test.colorDepth)
+		if err != nil {
+			t.Errorf("parseColorSpec(%q, %d) error = %v", test.spec, test.colorDepth, err)
+		} else if color != test.expected {
+			t.Errorf("parseColorSpec(%q, %d) = %q, expected %q", test.spec, test.colorDepth, color, test.expected)
+		}
+	}
+}
+
+func TestParseInvalidColorSpec(t *testing.T) {
+	for _, spec := range []string{"purple", "#12345", "#gggggg", "256", "-1", "bold+nope"} {
+		if color, err := parseColorSpec(spec, ColorDepth256); err == nil {
+			t.Errorf("parseColorSpec(%q) = %q, expected an error", spec, color)
+		}
+	}
+}
+
+func TestParseColorSpecWithoutColors(t *testing.T) {
+	colorsEnabled := ColorsEnabled
+	t.Cleanup(func() {
+		ColorsEnabled = colorsEnabled
+	})
+	ColorsEnabled = false
+
+	if color, err := parseColorSpec("bold+#ff0000", ColorDepthTrue); err != nil || color != "" {
+		t.Errorf("parseColorSpec() = %q, %v, expected no escape sequence", color, err)
+	}
+}
+
+func TestColorCodeConversion(t *testing.T) {
+	tests := []struct {
+		index      int
+		colorDepth int
+		expected   string
+	}{
+		{3, ColorDepth16, "33"},
+		{12, ColorDepth16, "94"},
+		{21, ColorDepth16, "34"},
+		{231, ColorDepth16, "97"},
+		{12, ColorDepth256, "38;5;12"},
+		{231, ColorDepthTrue, "38;5;231"},
+	}
+
+	for _, test := range tests {
+		if code := indexedColorCode(test.index, test.colorDepth); code != test.expected {
+			t.Errorf("indexedColorCode(%d, %d) = %q, expected %q", test.index, test.colorDepth, code, test.expected)
+		}
+	}
+
+	rgbTests := []struct {
+		rgb        [3]int
+		colorDepth int
+		expected   string
+	}{
+		{[3]int{255, 135, 0}, ColorDepthTrue, "38;2;255;135;0"},
+		{[3]int{255, 135, 0}, ColorDepth256, "38;5;208"},
+		{[3]int{255, 135, 0}, ColorDepth16, "33"},
+		{[3]int{128, 128, 128}, ColorDepth256, "38;5;244"},
+		{[3]int{250, 250, 250}, ColorDepth16, "97"},
+	}
+
+	for _, test := range rgbTests {
+		if code := rgbColorCode(test.rgb[0], test.rgb[1], test.rgb[2], test.colorDepth); code != test.expected {
+			t.Errorf("rgbColorCode(%v, %d) = %q, expected %q", test.rgb, test.colorDepth, code, test.expected)
+		}
+	}
+}
@@ -4,21 +4,44 @@ import (
 	"log"
 	"os"
 	"path"
+	"slices"
 
 	"gopkg.in/yaml.v3"
 )
 
 type StormfetchConfig struct {
-	Ascii                   string                   `yaml:"distro_ascii"`
-	DisableAmdgpuIdsWarning bool                     `yaml:"disable_amdgpu_ids_warning"`
-	Modules                 []stormfetchModuleConfig `yaml:"modules"`
-	AnsiiColors             []int                    `yaml:"ansii_colors"`
-	ForceConfigAnsii        bool                     `yaml:"force_config_ansii"`
+	Ascii                    string                   `yaml:"distro_ascii"`
+	ImageProtocol            string                   `yaml:"image_protocol"`
+	ImageWidth               int                      `yaml:"image_width"`
+	LogoPosition             string                   `yaml:"logo_position"`
+	LogoGap                  int                      `yaml:"logo_gap"`
+	LogoAlign                string                   `yaml:"logo_align"`
+	LogoSize                 string                   `yaml:"logo_size"`
+	AutoLayout               bool                     `yaml:"auto_layout"`
+	AlignValues              bool                     `yaml:"align_values"`
+	ValueSeparator           string                   `yaml:"value_separator"`
+	DisableAmdgpuIdsWarning  bool                     `yaml:"disable_amdgpu_ids_warning"`
+	Modules                  []stormfetchModuleConfig `yaml:"modules"`
+	ModuleTimeout            int                      `yaml:"module_timeout"`
+	ModuleTimeoutPlaceholder string                   `yaml:"module_timeout_placeholder"`
+	AnsiiColors              []string                 `yaml:"ansii_colors"`
+	ForceConfigAnsii         bool                     `yaml:"force_config_ansii"`
+	EnvPrefix                string                   `yaml:"env_prefix"`
 }
 
 var config = StormfetchConfig{
-	Ascii:   "auto",
-	Modules: make([]stormfetchModuleConfig, 0),
+	Ascii:          "auto",
+	ImageProtocol:  ImageProtocolAuto,
+	ImageWidth:     30,
+	LogoPosition:   LogoPositionLeft,
+	LogoGap:        3,
+	LogoAlign:      LogoAlignTop,
+	LogoSize:       LogoSizeAuto,
+	AutoLayout:     true,
+	ValueSeparator: ": ",
+	Modules:        make([]stormfetchModuleConfig, 0),
+	ModuleTimeout:  5000,
+	EnvPrefix:      "STORMFETCH_",
 }
 
 func readConfig() {
@@ -45,4 +68,41 @@ func readConfig() {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	// Validate layout options
+	if LogoPosition == "" {
+		LogoPosition = config.LogoPosition
+	}
+	if !slices.Contains(LogoPositions, LogoPosition) {
+		log.Fatalf("Unknown logo position: %s", LogoPosition)
+	}
+	if LogoSize == "" {
+		LogoSize = config.LogoSize
+	}
+	if !slices.Contains(LogoSizes, LogoSize) {
+		log.Fatalf("Unknown logo size: %s", LogoSize)
+	}
+	if !slices.Contains([]string{LogoAlignTop, LogoAlignCenter, LogoAlignBottom}, config.LogoAlign) {
+		log.Fatalf("Unknown logo alignment: %s", config.LogoAlign)
+	}
+
+	// Validate env output prefix
+	if EnvPrefix == "" {
+		EnvPrefix = config.EnvPrefix
+	}
+	if EnvPrefix != "" && !envPrefixRegex.MatchString(EnvPrefix) {
+		log.Fatalf("Invalid env prefix: %s", EnvPrefix)
+	}
+
+	// Validate module rules
+	for _, moduleConfig := range config.Modules {
+		for _, rule := range moduleConfig.Rules {
+			if _, err := parseRuleCondition(rule.When); err != nil {
+				log.Fatalf("Module '%s': %s", moduleConfig.Name, err)
+			}
+			if rule.Slot < 0 || rule.Slot > 9 {
+				log.Fatalf("Module '%s': invalid rule color slot: %d", moduleConfig.Name, rule.Slot)
+			}
+		}
+	}
 }
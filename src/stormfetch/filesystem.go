@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Abstraction over the filesystem detectors read system information from
+type SystemFilesystem interface {
+	ReadFile(name string) ([]byte, error)
+	ReadDir(name string) ([]os.DirEntry, error)
+	Stat(name string) (os.FileInfo, error)
+	EvalSymlinks(name string) (string, error)
+	LookPath(file string) (string, error)
+	Statfs(name string, buf *syscall.Statfs_t) error
+}
+
+// Filesystem rooted at a directory. All paths passed to it are absolute paths inside the root
+type rootFilesystem struct {
+	Root string
+}
+
+var SystemFS SystemFilesystem = rootFilesystem{Root: "/"}
+
+func NewSystemFilesystem(root string) SystemFilesystem {
+	return rootFilesystem{Root: root}
+}
+
+func (rfs rootFilesystem) realPath(name string) string {
+	return filepath.Join(rfs.Root, path.Clean("/"+name))
+}
+
+func (rfs rootFilesystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(rfs.realPath(name))
+}
+
+func (rfs rootFilesystem) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(rfs.realPath(name))
+}
+
+func (rfs rootFilesystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(rfs.realPath(name))
+}
+
+func (rfs rootFilesystem) Statfs(name string, buf *syscall.Statfs_t) error {
+	// Use filesystem statistics recorded in a system capture
+	if replayedStatfs != nil {
+		stats, ok := replayedStatfs[path.Clean("/"+name)]
+		if !ok {
+			return syscall.ENOENT
+		}
+		*buf = syscall.Statfs_t{Type: stats.Type, Bsize: stats.Bsize, Blocks: stats.Blocks, Bfree: stats.Bfree, Bavail: stats.Bavail}
+		return nil
+	}
+
+	return syscall.Statfs(rfs.realPath(name), buf)
+}
+
+func (rfs rootFilesystem) EvalSymlinks(name string) (string, error) {
+	if rfs.Root == "/" {
+		return filepath.EvalSymlinks(name)
+	}
+
+	// Resolve symlinks without leaving the root directory
+	name = path.Clean("/" + name)
+	for range 40 {
+		info, err := os.Lstat(rfs.realPath(name))
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return name, nil
+		}
+
+		target, err := os.Readlink(rfs.realPath(name))
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			name = path.Clean(target)
+		} else {
+			name = path.Join(path.Dir(name), target)
+		}
+	}
+
+	return "", errors.New("too many levels of symbolic links")
+}
+
+func (rfs rootFilesystem) LookPath(file string) (string, error) {
+	if rfs.Root == "/" {
+		return exec.LookPath(file)
+	}
+
+	// Search for executable in PATH directories inside the root directory
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		executable := path.Join(dir, file)
+		if info, err := rfs.Stat(executable); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return executable, nil
+		}
+	}
+
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+// Returns true if detectors read the files of the running system, so that its commands and processes describe
+// the same system
+func isHostRoot() bool {
+	switch systemFS := SystemFS.(type) {
+	case rootFilesystem:
+		return filepath.Clean(systemFS.Root) == "/"
+	case capturingFilesystem:
+		return filepath.Clean(systemFS.Root) == "/"
+	}
+
+	return false
+}
+
+// Sets the root directory detectors read system information from
+func setSysroot(root string) {
+	SystemFS = NewSystemFilesystem(root)
+
+	// Replay command outputs if the root is a system capture
+	loadReplayedCommands()
+
+	// Make ghw read hardware information from the same root
+	if strings.TrimRight(root, "/") != "" {
+		os.Setenv("GHW_CHROOT", root)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// Uses a fixture root from testdata/sysroot as the system filesystem for the duration of the test
+func useFixtureRoot(t *testing.T, name string) {
+	t.Helper()
+
+	previousFS, previousCommands, previousStatfs, previousSession := SystemFS, replayedCommands, replayedStatfs, replayedSession
+	t.Cleanup(func() {
+		SystemFS, replayedCommands, replayedStatfs, replayedSession = previousFS, previousCommands, previousStatfs, previousSession
+	})
+
+	SystemFS = NewSystemFilesystem(filepath.Join("testdata", "sysroot", name))
+	replayedCommands, replayedStatfs, replayedSession = nil, nil, nil
+	loadReplayedCommands()
+}
+
+func TestGetMountedPartitions(t *testing.T) {
+	useFixtureRoot(t, "debian")
+
+	expected := []partition{
+		{Device: "/dev/vda1", MountPoint: "/", FileystemType: "ext4", TotalSize: 107374182400, UsedSize: 53687091200, FreeSize: 53687091200},
+		{Device: "/dev/vda2", MountPoint: "/home", FileystemType: "ext4", TotalSize: 214748364800, UsedSize: 53687091200, FreeSize: 161061273600},
+	}
+
+	partitions := GetMountedPartitions(nil, nil)
+	if len(partitions) != len(expected) {
+		t.Fatalf("GetMountedPartitions() = %+v, expected %+v", partitions, expected)
+	}
+	for i := range expected {
+		if partitions[i] != expected[i] {
+			t.Errorf("GetMountedPartitions()[%d] = %+v, expected %+v", i, partitions[i], expected[i])
+		}
+	}
+
+	if partitions := GetMountedPartitions([]string{"/dev/vda1"}, []string{"ext4"}); len(partitions) != 0 {
+		t.Errorf("GetMountedPartitions() with hidden partitions = %+v, expected none", partitions)
+	}
+}
+
+func TestReplayCapturedSession(t *testing.T) {
+	useFixtureRoot(t, "capture")
+
+	if shell := GetShell(); shell != "Bash 5.2.15(1)-release" {
+		t.Errorf("GetShell() = %q, expected %q", shell, "Bash 5.2.15(1)-release")
+	}
+	if initSystem := GetInitSystem(); initSystem != "Systemd 252" {
+		t.Errorf("GetInitSystem() = %q, expected %q", initSystem, "Systemd 252")
+	}
+	if dewm := GetDEWM(); dewm != (DEWM{Name: "Sway 1.8.1", Type: "WM"}) {
+		t.Errorf("GetDEWM() = %+v, expected Sway 1.8.1", dewm)
+	}
+	if protocol := GetDisplayProtocol(); protocol != "Wayland" {
+		t.Errorf("GetDisplayProtocol() = %q, expected %q", protocol, "Wayland")
+	}
+}
@@ -13,27 +13,29 @@ import (
 )
 
 type CPU struct {
-	Model   string
-	Cores   int
-	Threads int
+	Model   string `json:"model" yaml:"model"`
+	Cores   int    `json:"cores" yaml:"cores"`
+	Threads int    `json:"threads" yaml:"threads"`
 }
 
 type GPU struct {
-	PCIAddress string
-	Vendor     string
-	Name       string
-	Product    string
-	Subsystem  string
-	Driver     string
-	VramTotal  string
-	VramUsed   string
+	PCIAddress     string `json:"pci_address" yaml:"pci_address"`
+	Vendor         string `json:"vendor" yaml:"vendor"`
+	Name           string `json:"name" yaml:"name"`
+	Product        string `json:"product" yaml:"product"`
+	Subsystem      string `json:"subsystem" yaml:"subsystem"`
+	Driver         string `json:"driver" yaml:"driver"`
+	VramTotal      string `json:"vram_total" yaml:"vram_total"`
+	VramUsed       string `json:"vram_used" yaml:"vram_used"`
+	VramTotalBytes uint64 `json:"vram_total_bytes" yaml:"vram_total_bytes"`
+	VramUsedBytes  uint64 `json:"vram_used_bytes" yaml:"vram_used_bytes"`
 }
 
 type Monitor struct {
-	Name        string
-	Width       int
-	Height      int
-	RefreshRate int
+	Name        string `json:"name" yaml:"name"`
+	Width       int    `json:"width" yaml:"width"`
+	Height      int    `json:"height" yaml:"height"`
+	RefreshRate int    `json:"refresh_rate" yaml:"refresh_rate"`
 }
 
 func GetCPUs(hiddenCPUs []int) []CPU {
@@ -77,18 +79,12 @@ func GetCPUs(hiddenCPUs []int) []CPU {
 func GetGPUModels(hiddenGPUs []int) []GPU {
 	ret := make([]GPU, 0)
 
-	// Set stderr to nil to avoid warnings
-	stderr := os.Stderr
-	os.Stderr = nil
-
-	gpus, err := ghw.GPU()
+	// Disable ghw warnings instead of printing them to stderr
+	gpus, err := ghw.GPU(ghw.WithDisableWarnings())
 	if err != nil {
 		return ret
 	}
 
-	// Restore stderr
-	os.Stderr = stderr
-
 	for i, gpu := range gpus.GraphicsCards {
 		if slices.Contains(hiddenGPUs, i+1) {
 			continue
@@ -132,8 +128,8 @@ func GetGPUModels(hiddenGPUs []int) []GPU {
 			if err == nil && !config.DisableAmdgpuIdsWarning {
 				name = fetchedName
 			} else {
-				fmt.Println("Warning: could not fetch GPU name from amdgpu.ids database! Error: " + err.Error())
-				fmt.Println("         You can disable this warning in the configuration file")
+				fmt.Fprintln(os.Stderr, "Warning: could not fetch GPU name from amdgpu.ids database! Error: "+err.Error())
+				fmt.Fprintln(os.Stderr, "         You can disable this warning in the configuration file")
 			}
 		}
 
@@ -156,27 +152,31 @@ func GetGPUModels(hiddenGPUs []int) []GPU {
 
 		// Get VRAM
 		vramTotal := "Unknown"
-		bytes, err := os.ReadFile("/sys/class/drm/card" + strconv.Itoa(gpu.Index) + "/device/mem_info_vram_total")
+		var vramTotalBytes uint64
+		bytes, err := SystemFS.ReadFile("/sys/class/drm/card" + strconv.Itoa(gpu.Index) + "/device/mem_info_vram_total")
 		if err == nil {
-			vramUint, _ := strconv.ParseUint(strings.TrimSpace(string(bytes)), 10, 64)
-			vramTotal = FormatBytes(vramUint)
+			vramTotalBytes, _ = strconv.ParseUint(strings.TrimSpace(string(bytes)), 10, 64)
+			vramTotal = FormatBytes(vramTotalBytes)
 		}
 		vramUsed := "Unknown"
-		bytes, err = os.ReadFile("/sys/class/drm/card" + strconv.Itoa(gpu.Index) + "/device/mem_info_vram_used")
+		var vramUsedBytes uint64
+		bytes, err = SystemFS.ReadFile("/sys/class/drm/card" + strconv.Itoa(gpu.Index) + "/device/mem_info_vram_used")
 		if err == nil {
-			vramUint, _ := strconv.ParseUint(strings.TrimSpace(string(bytes)), 10, 64)
-			vramUsed = FormatBytes(vramUint)
+			vramUsedBytes, _ = strconv.ParseUint(strings.TrimSpace(string(bytes)), 10, 64)
+			vramUsed = FormatBytes(vramUsedBytes)
 		}
 
 		ret = append(ret, GPU{
-			PCIAddress: gpu.Address,
-			Vendor:     vendor,
-			Name:       name,
-			Product:    gpu.DeviceInfo.Product.Name,
-			Subsystem:  gpu.DeviceInfo.Subsystem.Name,
-			Driver:     gpu.DeviceInfo.Driver,
-			VramTotal:  vramTotal,
-			VramUsed:   vramUsed,
+			PCIAddress:     gpu.Address,
+			Vendor:         vendor,
+			Name:           name,
+			Product:        gpu.DeviceInfo.Product.Name,
+			Subsystem:      gpu.DeviceInfo.Subsystem.Name,
+			Driver:         gpu.DeviceInfo.Driver,
+			VramTotal:      vramTotal,
+			VramUsed:       vramUsed,
+			VramTotalBytes: vramTotalBytes,
+			VramUsedBytes:  vramUsedBytes,
 		})
 	}
 
@@ -184,7 +184,7 @@ func GetGPUModels(hiddenGPUs []int) []GPU {
 }
 
 func GetMotherboardModel() string {
-	bytes, err := os.ReadFile("/sys/devices/virtual/dmi/id/board_name")
+	bytes, err := SystemFS.ReadFile("/sys/devices/virtual/dmi/id/board_name")
 	if err != nil {
 		return ""
 	}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	ImageProtocolAuto   = "auto"
+	ImageProtocolKitty  = "kitty"
+	ImageProtocolITerm2 = "iterm2"
+	ImageProtocolSixel  = "sixel"
+	ImageProtocolNone   = "none"
+)
+
+type imageLogo struct {
+	// Escape sequence that draws the image at the cursor
+	Sequence string
+	// Size of the image in terminal cells
+	Width  int
+	Height int
+}
+
+func isImageLogoPath(asciiName string) bool {
+	return strings.HasSuffix(strings.ToLower(asciiName), ".png")
+}
+
+// Returns the configured image logo or nil if text art should be used instead
+func GetDistroImageLogo() *imageLogo {
+	// Get image path to use
+	imagePath := config.Ascii
+	if Ascii != "" {
+		imagePath = Ascii
+	}
+	if !isImageLogoPath(imagePath) {
+		return nil
+	}
+
+	// Resolve image path relative to the config file
+	if strings.HasPrefix(imagePath, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		imagePath = path.Join(homeDir, imagePath[2:])
+	} else if !path.IsAbs(imagePath) && Ascii == "" {
+		imagePath = path.Join(path.Dir(ConfigPath), imagePath)
+	}
+
+	// Find graphics protocol to use
+	protocol := config.ImageProtocol
+	if protocol == "" || protocol == ImageProtocolAuto {
+		protocol = detectImageProtocol()
+	}
+	if protocol == ImageProtocolNone || !isTerminal(os.Stdout.Fd()) {
+		return nil
+	}
+
+	// Image escape sequences are not allowed when colors are disabled
+	if !ColorsEnabled {
+		return nil
+	}
+
+	// Read image
+	content, err := os.ReadFile(imagePath)
+	if err != nil {
+		return nil
+	}
+	img, err := png.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil
+	}
+
+	// Calculate image size in terminal cells
+	width := config.ImageWidth
+	if width <= 0 {
+		width = 30
+	}
+	cellWidth, cellHeight := getTerminalCellSize()
+	bounds := img.Bounds()
+	height := int(math.Round(float64(width*cellWidth) * float64(bounds.Dy()) / float64(bounds.Dx()) / float64(cellHeight)))
+	if height <= 0 {
+		height = 1
+	}
+
+	var sequence string
+	switch protocol {
+	case ImageProtocolKitty:
+		sequence = kittyImageSequence(content, width, height)
+	case ImageProtocolITerm2:
+		sequence = iterm2ImageSequence(content, width, height)
+	case ImageProtocolSixel:
+		sequence = sixelImageSequence(img, width*cellWidth, height*cellHeight)
+	default:
+		return nil
+	}
+
+	return &imageLogo{
+		Sequence: sequence,
+		Width:    width,
+		Height:   height,
+	}
+}
+
+// Returns an escape sequence that reserves space for the output and draws the image at the given position without moving the cursor
+func (logo *imageLogo) placementSequence(lineCount int, position layoutPosition) string {
+	lineCount = max(lineCount, position.Row+logo.Height)
+
+	builder := strings.Builder{}
+	builder.WriteString(strings.Repeat("\n", lineCount))
+	builder.WriteString(fmt.Sprintf("\033[%dA\0337", lineCount))
+	if position.Row > 0 {
+		builder.WriteString(fmt.Sprintf("\033[%dB", position.Row))
+	}
+	if position.Column > 0 {
+		builder.WriteString(fmt.Sprintf("\033[%dC", position.Column))
+	}
+	builder.WriteString(logo.Sequence + "\0338")
+
+	return builder.String()
+}
+
+func detectImageProtocol() string {
+	term := os.Getenv("TERM")
+	termProgram := os.Getenv("TERM_PROGRAM")
+
+	switch {
+	case term == "xterm-kitty" || os.Getenv("KITTY_WINDOW_ID") != "" || termProgram == "ghostty":
+		return ImageProtocolKitty
+	case termProgram == "iTerm.app" || termProgram == "WezTerm" || os.Getenv("LC_TERMINAL") == "iTerm2":
+		return ImageProtocolITerm2
+	case strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "mlterm") || strings.HasPrefix(term, "yaft"):
+		return ImageProtocolSixel
+	default:
+		return ImageProtocolNone
+	}
+}
+
+func kittyImageSequence(content []byte, width, height int) string {
+	encoded := base64.StdEncoding.EncodeToString(content)
+
+	// Transmit image in chunks of 4096 bytes
+	builder := strings.Builder{}
+	for i := 0; i < len(encoded); i += 4096 {
+		chunk := encoded[i:min(i+4096, len(encoded))]
+		more := 0
+		if i+4096 < len(encoded) {
+			more = 1
+		}
+
+		if i == 0 {
+			builder.WriteString(fmt.Sprintf("\033_Gf=100,a=T,q=2,C=1,c=%d,r=%d,m=%d;%s\033\\", width, height, more, chunk))
+		} else {
+			builder.WriteString(fmt.Sprintf("\033_Gm=%d;%s\033\\", more, chunk))
+		}
+	}
+
+	return builder.String()
+}
+
+func iterm2ImageSequence(content []byte, width, height int) string {
+	return fmt.Sprintf("\033]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a", len(content), width, height, base64.StdEncoding.EncodeToString(content))
+}
+
+func sixelImageSequence(img image.Image, width, height int) string {
+	bounds := img.Bounds()
+
+	// Scale image and quantize it to a 6x6x6 color cube
+	pixels := make([]int, width*height)
+	for y := range height {
+		for x := range width {
+			r, g, b, a := img.At(bounds.Min.X+x*bounds.Dx()/width, bounds.Min.Y+y*bounds.Dy()/height).RGBA()
+			if a < 0x8000 {
+				pixels[y*width+x] = -1
+				continue
+			}
+			pixels[y*width+x] = int(r*5/0xffff)*36 + int(g*5/0xffff)*6 + int(b*5/0xffff)
+		}
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("\033P0;1;0q\"1;1;%d;%d", width, height))
+
+	// Write palette
+	for i := range 216 {
+		builder.WriteString(fmt.Sprintf("#%d;2;%d;%d;%d", i, i/36*20, i/6%6*20, i%6*20))
+	}
+
+	// Write image in bands of 6 rows
+	for bandY := 0; bandY < height; bandY += 6 {
+		first := true
+		for color := range 216 {
+			// Build sixel row for color
+			row := make([]byte, width)
+			used := false
+			for x := range width {
+				bits := 0
+				for i := 0; i < 6 && bandY+i < height; i++ {
+					if pixels[(bandY+i)*width+x] == color {
+						bits |= 1 << i
+					}
+				}
+				if bits != 0 {
+					used = true
+				}
+				row[x] = byte(63 + bits)
+			}
+			if !used {
+				continue
+			}
+
+			if !first {
+				builder.WriteByte('$')
+			}
+			first = false
+			builder.WriteString("#" + strconv.Itoa(color))
+			writeSixelRow(&builder, row)
+		}
+		builder.WriteByte('-')
+	}
+
+	builder.WriteString("\033\\")
+	return builder.String()
+}
+
+// Writes a sixel row using run-length encoding
+func writeSixelRow(builder *strings.Builder, row []byte) {
+	for i := 0; i < len(row); {
+		count := 1
+		for i+count < len(row) && row[i+count] == row[i] {
+			count++
+		}
+
+		if count > 3 {
+			builder.WriteString("!" + strconv.Itoa(count))
+			builder.WriteByte(row[i])
+		} else {
+			for range count {
+				builder.WriteByte(row[i])
+			}
+		}
+		i += count
+	}
+}
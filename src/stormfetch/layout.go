@@ -0,0 +1,224 @@
+package main
+
+import (
+	"strings"
+)
+
+const (
+	LogoPositionLeft  = "left"
+	LogoPositionRight = "right"
+	LogoPositionAbove = "above"
+	LogoPositionBelow = "below"
+	LogoPositionNone  = "none"
+)
+
+var LogoPositions = []string{LogoPositionLeft, LogoPositionRight, LogoPositionAbove, LogoPositionBelow, LogoPositionNone}
+
+const (
+	LogoAlignTop    = "top"
+	LogoAlignCenter = "center"
+	LogoAlignBottom = "bottom"
+)
+
+// Lines of text along with their width in terminal cells
+type layoutBlock struct {
+	Lines  []string
+	Widths []int
+	Width  int
+}
+
+// Position of the logo inside the composed output
+type layoutPosition struct {
+	Row    int
+	Column int
+}
+
+func newLayoutBlock(lines []string, plainLines []string) layoutBlock {
+	block := layoutBlock{Lines: lines, Widths: make([]int, len(lines))}
+	for i, line := range plainLines {
+		block.Widths[i] = displayWidth(line)
+		block.Width = max(block.Width, block.Widths[i])
+	}
+
+	return block
+}
+
+func (block layoutBlock) Height() int {
+	return len(block.Lines)
+}
+
+// Returns the line at the given row padded to the block's width, or blank space if the row is outside the block
+func (block layoutBlock) paddedLine(row int) string {
+	if row < 0 || row >= len(block.Lines) {
+		return strings.Repeat(" ", block.Width)
+	}
+
+	return block.Lines[row] + strings.Repeat(" ", block.Width-block.Widths[row])
+}
+
+// Returns the offset of a block of the given height inside the available height
+func alignmentOffset(height, availableHeight int, align string) int {
+	switch align {
+	case LogoAlignCenter:
+		return (availableHeight - height) / 2
+	case LogoAlignBottom:
+		return availableHeight - height
+	default:
+		return 0
+	}
+}
+
+// Combines the logo and module text into the final output lines
+func composeLayout(logo, modules layoutBlock, position, align string, gap int) ([]string, layoutPosition) {
+	lines := make([]string, 0)
+	gapText := strings.Repeat(" ", max(gap, 0))
+
+	switch position {
+	case LogoPositionNone:
+		return modules.Lines, layoutPosition{}
+	case LogoPositionAbove, LogoPositionBelow:
+		first, second := logo, modules
+		if position == LogoPositionBelow {
+			first, second = modules, logo
+		}
+
+		lines = append(lines, first.Lines...)
+		if first.Height() > 0 && second.Height() > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, second.Lines...)
+
+		if position == LogoPositionBelow {
+			return lines, layoutPosition{Row: len(lines) - logo.Height()}
+		}
+		return lines, layoutPosition{}
+	}
+
+	// Align the shorter block vertically next to the taller one
+	height := max(logo.Height(), modules.Height())
+	logoOffset := alignmentOffset(logo.Height(), height, align)
+	modulesOffset := alignmentOffset(modules.Height(), height, align)
+
+	for row := range height {
+		moduleRow := row - modulesOffset
+		if position == LogoPositionRight {
+			line := modules.paddedLine(moduleRow) + gapText + logo.paddedLine(row-logoOffset)
+			lines = append(lines, strings.TrimRight(line, " "))
+			continue
+		}
+
+		line := logo.paddedLine(row-logoOffset) + gapText
+		if moduleRow >= 0 && moduleRow < modules.Height() {
+			line += modules.Lines[moduleRow]
+		}
+		lines = append(lines, line)
+	}
+
+	if position == LogoPositionRight {
+		return lines, layoutPosition{Row: logoOffset, Column: modules.Width + len(gapText)}
+	}
+	return lines, layoutPosition{Row: logoOffset}
+}
+
+// Creates a layout block from ascii art without its header
+func newAsciiArtBlock(asciiArt string, colorMap []string) layoutBlock {
+	if asciiArt == "" {
+		return newLayoutBlock(nil, nil)
+	}
+
+	// Rewrite last color code to the start of the next line
+	lastColor := "%0"
+	asciiArtLines := strings.Split(asciiArt, "\n")
+	for i, line := range asciiArtLines {
+		if i > 0 {
+			asciiArtLines[i] = lastColor + line
+		}
+		if colorCodes := colorCodeRegex.FindAllString(line, -1); len(colorCodes) > 0 {
+			lastColor = colorCodes[len(colorCodes)-1]
+		}
+	}
+
+	// Replace colors in ascii art
+	coloredLines := make([]string, len(asciiArtLines))
+	plainLines := make([]string, len(asciiArtLines))
+	for i, line := range asciiArtLines {
+		coloredLines[i] = replaceColorCodes(line, colorMap)
+		plainLines[i] = removeColorCodes(line)
+	}
+
+	return newLayoutBlock(coloredLines, plainLines)
+}
+
+// Checks whether the logo and modules fit in the terminal width at the given position
+func fitsLayout(logo, modules layoutBlock, position string, gap int, columns int) bool {
+	if position == LogoPositionLeft || position == LogoPositionRight {
+		return logo.Width+gap+modules.Width <= columns
+	}
+	return logo.Width <= columns
+}
+
+// Picks the first layout that fits in the terminal, trying each logo from largest to smallest at the configured position and then above the modules before showing modules only
+func fitLayout(logos []layoutBlock, modules layoutBlock, position string, gap int, columns int) (layoutBlock, string) {
+	if position == LogoPositionNone {
+		return logos[0], position
+	}
+
+	for _, logo := range logos {
+		if fitsLayout(logo, modules, position, gap, columns) {
+			return logo, position
+		}
+
+		// Move the logo above the modules
+		if (position == LogoPositionLeft || position == LogoPositionRight) && fitsLayout(logo, modules, LogoPositionAbove, gap, columns) {
+			return logo, LogoPositionAbove
+		}
+	}
+
+	return newLayoutBlock(nil, nil), LogoPositionNone
+}
+
+// Truncates module lines that don't fit next to the logo
+func truncateModules(modules layoutBlock, logo layoutBlock, position string, gap int, columns int) layoutBlock {
+	availableWidth := columns
+	if position == LogoPositionLeft || position == LogoPositionRight {
+		availableWidth -= logo.Width + gap
+	}
+
+	lines := make([]string, len(modules.Lines))
+	plainLines := make([]string, len(modules.Lines))
+	for i, line := range modules.Lines {
+		lines[i] = truncateDisplayWidth(line, availableWidth)
+		plainLines[i] = StripAnsii(lines[i])
+	}
+
+	return newLayoutBlock(lines, plainLines)
+}
+
+// Pads the labels of module lines containing the separator to a common width
+func alignModuleValues(lines []string, separator string) []string {
+	if separator == "" {
+		return lines
+	}
+
+	// Find widest label
+	labelWidth := 0
+	for _, line := range lines {
+		if label, _, ok := strings.Cut(line, separator); ok {
+			labelWidth = max(labelWidth, displayWidth(removeColorCodes(label)))
+		}
+	}
+
+	// Insert padding after the separator so that it stays next to the label
+	alignedLines := make([]string, len(lines))
+	for i, line := range lines {
+		label, value, ok := strings.Cut(line, separator)
+		if !ok {
+			alignedLines[i] = line
+			continue
+		}
+		padding := strings.Repeat(" ", labelWidth-displayWidth(removeColorCodes(label)))
+		alignedLines[i] = label + separator + padding + value
+	}
+
+	return alignedLines
+}
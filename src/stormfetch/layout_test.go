@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Creates a layout block of the given size filled with x characters
+func newTestBlock(width, height int) layoutBlock {
+	lines := make([]string, height)
+	for i := range lines {
+		lines[i] = strings.Repeat("x", width)
+	}
+	return newLayoutBlock(lines, lines)
+}
+
+func TestFitLayout(t *testing.T) {
+	large, small := newTestBlock(50, 20), newTestBlock(10, 5)
+	modules := newTestBlock(30, 10)
+
+	tests := []struct {
+		name             string
+		position         string
+		columns          int
+		expectedLogo     layoutBlock
+		expectedPosition string
+	}{
+		{"side by side", LogoPositionLeft, 90, large, LogoPositionLeft},
+		{"right side", LogoPositionRight, 83, large, LogoPositionRight},
+		{"logo above modules", LogoPositionLeft, 82, large, LogoPositionAbove},
+		{"smaller variant", LogoPositionLeft, 45, small, LogoPositionLeft},
+		{"smaller variant above modules", LogoPositionRight, 20, small, LogoPositionAbove},
+		{"configured above", LogoPositionAbove, 50, large, LogoPositionAbove},
+		{"configured below", LogoPositionBelow, 49, small, LogoPositionBelow},
+		{"modules only", LogoPositionLeft, 9, newLayoutBlock(nil, nil), LogoPositionNone},
+		{"no logo", LogoPositionNone, 9, large, LogoPositionNone},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logo, position := fitLayout([]layoutBlock{large, small}, modules, test.position, 3, test.columns)
+			if position != test.expectedPosition || !reflect.DeepEqual(logo, test.expectedLogo) {
+				t.Errorf("fitLayout() = %dx%d logo %s, expected %dx%d logo %s", logo.Width, logo.Height(), position,
+					test.expectedLogo.Width, test.expectedLogo.Height(), test.expectedPosition)
+			}
+		})
+	}
+}
+
+func TestTruncateModules(t *testing.T) {
+	logo := newTestBlock(10, 2)
+	lines := []string{"\033[31mDistribution: \033[0mDebian GNU/Linux", "Kernel: 6.1", ""}
+	plainLines := make([]string, len(lines))
+	for i, line := range lines {
+		plainLines[i] = StripAnsii(line)
+	}
+	modules := newLayoutBlock(lines, plainLines)
+
+	tests := []struct {
+		position string
+		columns  int
+		expected []string
+	}{
+		{LogoPositionLeft, 43, lines},
+		{LogoPositionLeft, 33, []string{"\033[31mDistribution: \033[0mDebia…", "Kernel: 6.1", ""}},
+		{LogoPositionRight, 23, []string{"\033[31mDistribut…", "Kernel: 6…", ""}},
+		{LogoPositionAbove, 20, []string{"\033[31mDistribution: \033[0mDebia…", "Kernel: 6.1", ""}},
+	}
+
+	for _, test := range tests {
+		truncated := truncateModules(modules, logo, test.position, 3, test.columns)
+		if !reflect.DeepEqual(truncated.Lines, test.expected) {
+			t.Errorf("truncateModules(%s, %d) = %q, expected %q", test.position, test.columns, truncated.Lines, test.expected)
+		}
+		for i, width := range truncated.Widths {
+			if width != displayWidth(StripAnsii(truncated.Lines[i])) {
+				t.Errorf("truncateModules(%s, %d) width of line %d = %d, expected %d", test.position, test.columns, i, width, displayWidth(StripAnsii(truncated.Lines[i])))
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+type LoadAverage struct {
+	Load1        float64 `json:"load_1" yaml:"load_1"`
+	Load5        float64 `json:"load_5" yaml:"load_5"`
+	Load15       float64 `json:"load_15" yaml:"load_15"`
+	RunningTasks int     `json:"running_tasks" yaml:"running_tasks"`
+	TotalTasks   int     `json:"total_tasks" yaml:"total_tasks"`
+	Processes    int     `json:"processes" yaml:"processes"`
+}
+
+func GetLoadAverage() *LoadAverage {
+	bytes, err := SystemFS.ReadFile("/proc/loadavg")
+	if err != nil {
+		return nil
+	}
+
+	// Parse load averages and task counts such as "0.13 0.12 0.07 2/72 9326"
+	fields := strings.Fields(string(bytes))
+	if len(fields) < 4 {
+		return nil
+	}
+	loadAverage := LoadAverage{}
+	for i, load := range []*float64{&loadAverage.Load1, &loadAverage.Load5, &loadAverage.Load15} {
+		if *load, err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return nil
+		}
+	}
+	if running, total, ok := strings.Cut(fields[3], "/"); ok {
+		loadAverage.RunningTasks, _ = strconv.Atoi(running)
+		loadAverage.TotalTasks, _ = strconv.Atoi(total)
+	}
+
+	// Count processes
+	if executables, err := getProcessExecutables(); err == nil {
+		loadAverage.Processes = len(executables)
+	}
+
+	return &loadAverage
+}
+
+// Returns the load averages as a percentage of the CPU thread count
+func (loadAverage LoadAverage) Percentages() []float64 {
+	threads := 0
+	for _, cpu := range GetCPUs(nil) {
+		threads += cpu.Threads
+	}
+	if threads == 0 {
+		return nil
+	}
+
+	return []float64{
+		loadAverage.Load1 / float64(threads) * 100,
+		loadAverage.Load5 / float64(threads) * 100,
+		loadAverage.Load15 / float64(threads) * 100,
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetLoadAverage(t *testing.T) {
+	tests := []struct {
+		root     string
+		expected *LoadAverage
+	}{
+		{"debian", &LoadAverage{Load1: 0.52, Load5: 0.58, Load15: 0.59, RunningTasks: 2, TotalTasks: 467}},
+		{"alpine", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.root, func(t *testing.T) {
+			useFixtureRoot(t, test.root)
+			if loadAverage := GetLoadAverage(); !reflect.DeepEqual(loadAverage, test.expected) {
+				t.Errorf("GetLoadAverage() = %+v, expected %+v", loadAverage, test.expected)
+			}
+		})
+	}
+}
@@ -3,9 +3,9 @@ package main
 import (
 	"flag"
 	"fmt"
-	"strconv"
+	"log"
+	"slices"
 	"strings"
-	"time"
 	"unicode"
 )
 
@@ -19,6 +19,14 @@ var ShowVersion = false
 var ConfigPath = ""
 var Ascii = ""
 var ShowModuleTimeTaken = false
+var JsonOutput = false
+var OutputFormat = OutputFormatText
+var Sysroot = ""
+var CapturePath = ""
+var ColorMode = ColorModeAuto
+var LogoPosition = ""
+var LogoSize = ""
+var EnvPrefix = ""
 
 func main() {
 	parseFlags()
@@ -32,7 +40,35 @@ func parseFlags() {
 	flag.StringVar(&ConfigPath, "config", "", "Use the specified config file")
 	flag.BoolVar(&ShowModuleTimeTaken, "time-taken", false, "Show time taken to execute each module")
 	flag.StringVar(&Ascii, "ascii", "", "Set distro ascii")
+	flag.BoolVar(&JsonOutput, "json", false, "Output system information as JSON (same as --output-format json)")
+	flag.StringVar(&Sysroot, "sysroot", "", "Read system information from the specified root directory")
+	flag.StringVar(&CapturePath, "capture", "", "Capture files and command outputs used to detect system information into a .tar.gz archive")
+	flag.StringVar(&ColorMode, "color", ColorModeAuto, "Set when to use colors ("+strings.Join(ColorModes, ", ")+")")
+	flag.StringVar(&LogoPosition, "logo-position", "", "Set logo position ("+strings.Join(LogoPositions, ", ")+")")
+	flag.StringVar(&LogoSize, "logo-size", "", "Set logo size ("+strings.Join(LogoSizes, ", ")+")")
+	flag.StringVar(&OutputFormat, "output-format", OutputFormatText, "Set output format ("+strings.Join(OutputFormats, ", ")+")")
+	flag.StringVar(&EnvPrefix, "env-prefix", "", "Prefix variable names in env output so that evaluating it doesn't overwrite variables like SHELL or HOSTNAME (STORMFETCH_ by default)")
 	flag.Parse()
+
+	if JsonOutput {
+		// Reject conflicting output formats instead of silently preferring JSON
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "output-format" && OutputFormat != OutputFormatJSON {
+				log.Fatalf("--json can't be used with --output-format %s", OutputFormat)
+			}
+		})
+		OutputFormat = OutputFormatJSON
+	}
+	if !slices.Contains(OutputFormats, OutputFormat) {
+		log.Fatalf("Unknown output format: %s", OutputFormat)
+	}
+	if !slices.Contains(ColorModes, ColorMode) {
+		log.Fatalf("Unknown color mode: %s", ColorMode)
+	}
+	ColorsEnabled = resolveColorMode(ColorMode)
+	if Sysroot != "" {
+		setSysroot(Sysroot)
+	}
 }
 
 func run() {
@@ -41,120 +77,154 @@ func run() {
 		return
 	}
 
-	// Fetch ascii art and remove header
-	asciiArt := GetDistroAsciiArt()
-	asciiArtHeader := ""
-	if strings.HasPrefix(asciiArt, "#/") {
-		asciiArtHeader = strings.SplitN(asciiArt, "\n", 2)[0]
-		asciiArt = strings.SplitN(asciiArt, "\n", 2)[1]
+	// Capture system information for bug reports
+	if CapturePath != "" {
+		captureSystem(CapturePath)
+		return
 	}
-	asciiArtNoColor := asciiArt
 
-	// Rewrite last color code to the start of the next line
-	lastColor := "0"
-	for i := 1; i < len(asciiArt); i++ {
-		rune := rune(asciiArt[i])
-
-		if unicode.IsDigit(rune) && asciiArt[i-1] == '%' {
-			lastColor = string(rune)
-		}
+	// Print machine-readable output without ascii art
+	switch OutputFormat {
+	case OutputFormatJSON:
+		printJSON()
+		return
+	case OutputFormatYAML:
+		printYAML()
+		return
+	case OutputFormatEnv:
+		printEnv()
+		return
+	}
 
-		if rune == '\n' {
-			asciiArt = asciiArt[:i+1] + "%" + lastColor + asciiArt[i+1:]
-		}
+	// Fetch image logo or ascii art and remove header
+	var logo *imageLogo
+	if LogoPosition != LogoPositionNone {
+		logo = GetDistroImageLogo()
+	}
+	var asciiArt, asciiName string
+	if logo != nil {
+		// Fill the area covered by the image with blank ascii art
+		asciiArt = strings.TrimSuffix(strings.Repeat(strings.Repeat(" ", logo.Width)+"\n", logo.Height), "\n")
+	} else {
+		asciiArt, asciiName = GetDistroAsciiArt(LogoSize)
 	}
+	asciiArtHeader, asciiArt := splitAsciiArtHeader(asciiArt)
 
 	// Setup color map and replace colors in ascii art
 	colorMap := setupColorMap(asciiArtHeader)
-	for key, value := range colorMap {
-		asciiArt = strings.ReplaceAll(asciiArt, "%"+strconv.Itoa(key), value)
-		asciiArtNoColor = strings.ReplaceAll(asciiArtNoColor, "%"+strconv.Itoa(key), "")
-	}
-
-	// Execute modules in order
-	modulesText := make([]string, 0)
-	for _, moduleConfig := range config.Modules {
-		module, ok := Modules[moduleConfig.Name]
-		if !ok {
-			continue
-		}
-
-		// Set module config options
-		if moduleConfig.Format != "" {
-			module.Format = moduleConfig.Format
-		}
-		if moduleConfig.Data != nil {
-			module.Data = moduleConfig.Data
-		}
+	asciiArtBlock := newAsciiArtBlock(asciiArt, colorMap)
 
-		// Execute module
-		start := time.Now().UnixMilli()
-		text := module.Execute(module)
-		end := time.Now().UnixMilli()
-
-		// Insert default color at the start of the module's text
-		text = colorMap[0] + text
+	// Execute modules concurrently
+	results := executeModules(config.Modules, OutputFormatText)
 
+	// Collect module output in config order
+	moduleLines := make([]string, 0)
+	for _, result := range results {
 		// Show time taken
 		if ShowModuleTimeTaken {
-			fmt.Printf("Module '%s' took %d milliseconds\n", module.Name, end-start)
+			if result.TimedOut {
+				fmt.Printf("Module '%s' timed out after %d milliseconds\n", result.Name, result.TimeTaken)
+			} else {
+				fmt.Printf("Module '%s' took %d milliseconds\n", result.Name, result.TimeTaken)
+			}
 		}
 
-		// Replace colors in returned string
-		textNoColor := text
-		for key, value := range colorMap {
-			text = strings.ReplaceAll(text, "%"+strconv.Itoa(key), value)
-			textNoColor = strings.ReplaceAll(textNoColor, "%"+strconv.Itoa(key), value)
+		// Remove escape sequences from module values such as command outputs if colors are disabled
+		text := result.Text
+		if !ColorsEnabled {
+			text = StripAnsii(text)
 		}
 
 		// Continue if text length is 0
-		if len(textNoColor)-len(colorMap[0]) == 0 {
+		if strings.TrimSpace(removeColorCodes(text)) == "" {
 			continue
 		}
 
-		// Add text to slice
-		for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
-			modulesText = append(modulesText, line)
-		}
+		moduleLines = append(moduleLines, strings.Split(strings.TrimRightFunc(text, unicode.IsSpace), "\n")...)
 	}
 
-	// Get longest line in ascii art
-	maxWidth := 0
-	for _, line := range strings.Split(asciiArtNoColor, "\n") {
-		if len(line) > maxWidth {
-			maxWidth = len(line)
-		}
+	// Line up values after the separator
+	if config.AlignValues {
+		moduleLines = alignModuleValues(moduleLines, config.ValueSeparator)
 	}
 
-	// Split ascii art into each line
-	asciiArtSplit := strings.Split(asciiArt, "\n")
-	asciiArtNoColorSplit := strings.Split(asciiArtNoColor, "\n")
-
-	// Get amount of lines to print
-	lineCount := max(len(asciiArtSplit), len(modulesText))
+	// Replace colors with the default color inserted at the start of each line
+	modulesText := make([]string, len(moduleLines))
+	for i, line := range moduleLines {
+		modulesText[i] = colorMap[0] + replaceColorCodes(line, colorMap)
+	}
 
-	// Combine ascii art and module text
-	final := strings.Builder{}
-	for i := range lineCount {
-		// Write ascii art
-		currentLineLength := 0
-		if i < len(asciiArtSplit) {
-			final.WriteString(asciiArtSplit[i])
-			currentLineLength += len(asciiArtNoColorSplit[i])
+	// Split module text into a block
+	modulesNoColor := make([]string, len(modulesText))
+	for i, line := range modulesText {
+		modulesNoColor[i] = StripAnsii(line)
+	}
+	modulesBlock := newLayoutBlock(modulesText, modulesNoColor)
+
+	// Fall back to a layout that fits the terminal width
+	position := LogoPosition
+	if size := getTerminalSize(); size != nil && config.AutoLayout {
+		// Use the large variant in auto mode if it fits
+		if logo == nil && LogoSize == LogoSizeAuto {
+			if largeLogo, ok := getAsciiArtVariantBlock(asciiName, LogoSizeLarge, colorMap); ok && fitsLayout(largeLogo, modulesBlock, position, config.LogoGap, int(size.Columns)) {
+				asciiArtBlock = largeLogo
+			}
 		}
 
-		// Write blank space between ascii art and module text
-		for i := currentLineLength; i < maxWidth+3; i++ {
-			final.WriteString(" ")
+		// Fall back to smaller variants
+		logos := []layoutBlock{asciiArtBlock}
+		if logo == nil && LogoSize == LogoSizeLarge {
+			if normalLogo, ok := getAsciiArtVariantBlock(asciiName, LogoSizeNormal, colorMap); ok {
+				logos = append(logos, normalLogo)
+			}
+		}
+		if logo == nil && LogoSize != LogoSizeSmall {
+			if smallLogo, ok := getAsciiArtVariantBlock(asciiName, LogoSizeSmall, colorMap); ok {
+				logos = append(logos, smallLogo)
+			}
 		}
 
-		// Write module text
-		if i < len(modulesText) {
-			final.WriteString(modulesText[i])
+		fittedBlock, fittedPosition := fitLayout(logos, modulesBlock, position, config.LogoGap, int(size.Columns))
+		if fittedPosition == LogoPositionNone {
+			logo = nil
 		}
+		asciiArtBlock, position = fittedBlock, fittedPosition
+		modulesBlock = truncateModules(modulesBlock, asciiArtBlock, position, config.LogoGap, int(size.Columns))
+	}
+
+	// Combine ascii art and module text
+	lines, logoPosition := composeLayout(asciiArtBlock, modulesBlock, position, config.LogoAlign, config.LogoGap)
+	final := strings.Builder{}
+	if logo != nil {
+		final.WriteString(logo.placementSequence(len(lines), logoPosition))
+	}
+	for _, line := range lines {
+		final.WriteString(line + "\n")
+	}
+
+	// Reset colors at the end of the output
+	if ColorsEnabled {
+		fmt.Println(strings.TrimRight(final.String(), "\n") + "\033[0m")
+	} else {
+		fmt.Println(strings.TrimRight(final.String(), "\n"))
+	}
+}
+
+// Returns a layout block for the given size variant of the ascii art with the given name. Variants are colored
+// using their own header, falling back to the color map of the ascii art they replace
+func getAsciiArtVariantBlock(asciiName, size string, colorMap []string) (layoutBlock, bool) {
+	if asciiName == "" {
+		return layoutBlock{}, false
+	}
+	asciiArt, ok := readAsciiArtVariant(asciiName, size)
+	if !ok {
+		return layoutBlock{}, false
+	}
 
-		final.WriteString("\n")
+	asciiArtHeader, asciiArt := splitAsciiArtHeader(asciiArt)
+	if asciiArtHeader != "" {
+		colorMap = setupColorMap(asciiArtHeader)
 	}
 
-	fmt.Println(strings.TrimRight(final.String(), "\n") + "\033[0m")
+	return newAsciiArtBlock(asciiArt, colorMap), true
 }
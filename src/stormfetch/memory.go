@@ -2,56 +2,245 @@ package main
 
 import (
 	"bufio"
-	"os"
+	"bytes"
+	"path"
 	"strconv"
 	"strings"
 )
 
+const (
+	MemoryUnitKiB  = "KiB"
+	MemoryUnitMiB  = "MiB"
+	MemoryUnitGiB  = "GiB"
+	MemoryUnitAuto = "auto"
+)
+
+const (
+	// MemTotal - MemAvailable
+	UsedMemoryFormulaAvailable = "available"
+	// MemTotal - MemFree - Buffers - Cached - SReclaimable + Shmem
+	UsedMemoryFormulaHtop = "htop"
+	// MemTotal - MemFree - Buffers - Cached - SReclaimable
+	UsedMemoryFormulaFree = "free"
+)
+
+// Memory sizes are in KiB
 type Memory struct {
-	MemTotal     int
-	MemFree      int
-	MemAvailable int
+	MemTotal       int          `json:"mem_total" yaml:"mem_total"`
+	MemFree        int          `json:"mem_free" yaml:"mem_free"`
+	MemAvailable   int          `json:"mem_available" yaml:"mem_available"`
+	Buffers        int          `json:"buffers" yaml:"buffers"`
+	Cached         int          `json:"cached" yaml:"cached"`
+	Shmem          int          `json:"shmem" yaml:"shmem"`
+	SReclaimable   int          `json:"sreclaimable" yaml:"sreclaimable"`
+	Dirty          int          `json:"dirty" yaml:"dirty"`
+	HugePagesTotal int          `json:"hugepages_total" yaml:"hugepages_total"`
+	HugePageSize   int          `json:"hugepage_size" yaml:"hugepage_size"`
+	SwapTotal      int          `json:"swap_total" yaml:"swap_total"`
+	SwapFree       int          `json:"swap_free" yaml:"swap_free"`
+	SwapDevices    []SwapDevice `json:"swap_devices" yaml:"swap_devices"`
+	Zram           []ZramDevice `json:"zram" yaml:"zram"`
 }
 
-func GetMemoryInfo() *Memory {
-	toInt := func(raw string) int {
-		if raw == "" {
-			return 0
-		}
-		res, err := strconv.Atoi(raw)
-		if err != nil {
-			panic(err)
-		}
-		return res
-	}
+type SwapDevice struct {
+	Name     string `json:"name" yaml:"name"`
+	Type     string `json:"type" yaml:"type"`
+	Size     int    `json:"size" yaml:"size"`
+	Used     int    `json:"used" yaml:"used"`
+	Priority int    `json:"priority" yaml:"priority"`
+}
 
-	parseLine := func(raw string) (key string, value int) {
-		text := strings.ReplaceAll(raw[:len(raw)-2], " ", "")
-		keyValue := strings.Split(text, ":")
-		return keyValue[0], toInt(keyValue[1])
-	}
+type ZramDevice struct {
+	Name           string `json:"name" yaml:"name"`
+	DiskSize       int    `json:"disk_size" yaml:"disk_size"`
+	OriginalSize   int    `json:"original_size" yaml:"original_size"`
+	CompressedSize int    `json:"compressed_size" yaml:"compressed_size"`
+	MemoryUsed     int    `json:"memory_used" yaml:"memory_used"`
+}
 
-	if _, err := os.Stat("/proc/meminfo"); err != nil {
+func GetMemoryInfo() *Memory {
+	if _, err := SystemFS.Stat("/proc/meminfo"); err != nil {
 		return nil
 	}
-	file, err := os.Open("/proc/meminfo")
+	content, err := SystemFS.ReadFile("/proc/meminfo")
 	if err != nil {
 		panic(err)
 	}
-	defer file.Close()
-	bufio.NewScanner(file)
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
 	res := Memory{}
 	for scanner.Scan() {
-		key, value := parseLine(scanner.Text())
+		// Parse lines such as "MemTotal: 16318788 kB" or "HugePages_Total: 0"
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+		size, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+
 		switch key {
 		case "MemTotal":
-			res.MemTotal = value / 1024
+			res.MemTotal = size
 		case "MemFree":
-			res.MemFree = value / 1024
+			res.MemFree = size
 		case "MemAvailable":
-			res.MemAvailable = value / 1024
+			res.MemAvailable = size
+		case "Buffers":
+			res.Buffers = size
+		case "Cached":
+			res.Cached = size
+		case "Shmem":
+			res.Shmem = size
+		case "SReclaimable":
+			res.SReclaimable = size
+		case "Dirty":
+			res.Dirty = size
+		case "HugePages_Total":
+			res.HugePagesTotal = size
+		case "Hugepagesize":
+			res.HugePageSize = size
+		case "SwapTotal":
+			res.SwapTotal = size
+		case "SwapFree":
+			res.SwapFree = size
 		}
 	}
+	res.SwapDevices = GetSwapDevices()
+	res.Zram = GetZramDevices()
+
 	return &res
 }
+
+// Returns used memory calculated using the given formula
+func (memory Memory) MemUsed(formula string) int {
+	switch formula {
+	case UsedMemoryFormulaHtop:
+		return memory.MemTotal - memory.MemFree - memory.Buffers - memory.Cached - memory.SReclaimable + memory.Shmem
+	case UsedMemoryFormulaFree:
+		return memory.MemTotal - memory.MemFree - memory.Buffers - memory.Cached - memory.SReclaimable
+	default:
+		return memory.MemTotal - memory.MemAvailable
+	}
+}
+
+func (memory Memory) SwapUsed() int {
+	return memory.SwapTotal - memory.SwapFree
+}
+
+// Formats a size in KiB using the given unit
+func FormatMemorySize(kib int, unit string) string {
+	switch unit {
+	case MemoryUnitKiB:
+		return strconv.Itoa(kib)
+	case MemoryUnitGiB:
+		return strconv.FormatFloat(float64(kib)/1024/1024, 'f', 2, 64)
+	case MemoryUnitAuto:
+		return FormatBytes(uint64(max(kib, 0)) * 1024)
+	default:
+		return strconv.Itoa(kib / 1024)
+	}
+}
+
+// Returns the unit label for sizes formatted with FormatMemorySize, which is empty for automatic units as
+// those include the unit in each value
+func MemoryUnitLabel(unit string) string {
+	switch unit {
+	case MemoryUnitKiB, MemoryUnitGiB:
+		return unit
+	case MemoryUnitAuto:
+		return ""
+	default:
+		return MemoryUnitMiB
+	}
+}
+
+func GetSwapDevices() []SwapDevice {
+	ret := make([]SwapDevice, 0)
+
+	content, err := SystemFS.ReadFile("/proc/swaps")
+	if err != nil {
+		return ret
+	}
+
+	// Skip header line
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		size, _ := strconv.Atoi(fields[2])
+		used, _ := strconv.Atoi(fields[3])
+		priority, _ := strconv.Atoi(fields[4])
+
+		ret = append(ret, SwapDevice{
+			Name:     strings.ReplaceAll(fields[0], "\\040", " "),
+			Type:     fields[1],
+			Size:     size,
+			Used:     used,
+			Priority: priority,
+		})
+	}
+
+	return ret
+}
+
+func GetZramDevices() []ZramDevice {
+	ret := make([]ZramDevice, 0)
+
+	dirEntries, err := SystemFS.ReadDir("/sys/block")
+	if err != nil {
+		return ret
+	}
+
+	for _, entry := range dirEntries {
+		if !strings.HasPrefix(entry.Name(), "zram") {
+			continue
+		}
+
+		// Skip devices that haven't been set up
+		diskSize := 0
+		if content, err := SystemFS.ReadFile(path.Join("/sys/block", entry.Name(), "disksize")); err == nil {
+			diskSize, _ = strconv.Atoi(strings.TrimSpace(string(content)))
+		}
+		if diskSize == 0 {
+			continue
+		}
+
+		// Read original data size, compressed data size and total memory used in bytes
+		content, err := SystemFS.ReadFile(path.Join("/sys/block", entry.Name(), "mm_stat"))
+		if err != nil {
+			continue
+		}
+		fields := strings.Fields(string(content))
+		if len(fields) < 3 {
+			continue
+		}
+		originalSize, _ := strconv.Atoi(fields[0])
+		compressedSize, _ := strconv.Atoi(fields[1])
+		memoryUsed, _ := strconv.Atoi(fields[2])
+
+		ret = append(ret, ZramDevice{
+			Name:           entry.Name(),
+			DiskSize:       diskSize / 1024,
+			OriginalSize:   originalSize / 1024,
+			CompressedSize: compressedSize / 1024,
+			MemoryUsed:     memoryUsed / 1024,
+		})
+	}
+
+	return ret
+}
+
+// Returns the zram compression ratio, or 0 if nothing is compressed
+func (zram ZramDevice) CompressionRatio() float64 {
+	if zram.CompressedSize == 0 {
+		return 0
+	}
+	return float64(zram.OriginalSize) / float64(zram.CompressedSize)
+}
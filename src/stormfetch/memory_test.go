@@ -0,0 +1,96 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetMemoryInfo(t *testing.T) {
+	tests := []struct {
+		root     string
+		expected *Memory
+	}{
+		{"debian", &Memory{
+			MemTotal:       16318788,
+			MemFree:        8123456,
+			MemAvailable:   12345678,
+			Buffers:        234567,
+			Cached:         3456789,
+			Shmem:          123456,
+			SReclaimable:   345678,
+			Dirty:          120,
+			HugePagesTotal: 0,
+			HugePageSize:   2048,
+			SwapTotal:      6291452,
+			SwapFree:       5767164,
+			SwapDevices: []SwapDevice{
+				{Name: "/dev/vda3", Type: "partition", Size: 2097148, Used: 524288, Priority: -2},
+				{Name: "/dev/zram0", Type: "partition", Size: 4194300, Used: 0, Priority: 100},
+				{Name: "/swap file", Type: "file", Size: 1048576, Used: 0, Priority: -3},
+			},
+			Zram: []ZramDevice{
+				{Name: "zram0", DiskSize: 4194304, OriginalSize: 1048576, CompressedSize: 262144, MemoryUsed: 278528},
+			},
+		}},
+		{"alpine", &Memory{
+			MemTotal:     2048000,
+			MemFree:      1024000,
+			MemAvailable: 1536000,
+			SwapDevices:  []SwapDevice{},
+			Zram:         []ZramDevice{},
+		}},
+		{"centos", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.root, func(t *testing.T) {
+			useFixtureRoot(t, test.root)
+			if memory := GetMemoryInfo(); !reflect.DeepEqual(memory, test.expected) {
+				t.Errorf("GetMemoryInfo() = %+v, expected %+v", memory, test.expected)
+			}
+		})
+	}
+}
+
+func TestMemUsed(t *testing.T) {
+	useFixtureRoot(t, "debian")
+	memory := GetMemoryInfo()
+
+	tests := []struct {
+		formula  string
+		expected int
+	}{
+		{UsedMemoryFormulaAvailable, 3973110},
+		{UsedMemoryFormulaHtop, 4281754},
+		{UsedMemoryFormulaFree, 4158298},
+	}
+
+	for _, test := range tests {
+		if used := memory.MemUsed(test.formula); used != test.expected {
+			t.Errorf("MemUsed(%q) = %d, expected %d", test.formula, used, test.expected)
+		}
+	}
+}
+
+func TestFormatMemorySize(t *testing.T) {
+	tests := []struct {
+		kib      int
+		unit     string
+		expected string
+		label    string
+	}{
+		{1536, MemoryUnitKiB, "1536", "KiB"},
+		{1536, MemoryUnitMiB, "1", "MiB"},
+		{1572864, MemoryUnitGiB, "1.50", "GiB"},
+		{1536, MemoryUnitAuto, "1.5 MiB", ""},
+	}
+
+	for _, test := range tests {
+		if size := FormatMemorySize(test.kib, test.unit); size != test.expected {
+			t.Errorf("FormatMemorySize(%d, %q) = %q, expected %q", test.kib, test.unit, size, test.expected)
+		}
+		if label := MemoryUnitLabel(test.unit); label != test.label {
+			t.Errorf("MemoryUnitLabel(%q) = %q, expected %q", test.unit, label, test.label)
+		}
+	}
+}
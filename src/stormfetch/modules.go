@@ -1,22 +1,43 @@
 package main
 
 import (
+	"context"
 	"os"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Used to declare modules in config files
 type stormfetchModuleConfig struct {
-	Name   string         `yaml:"name"`
-	Format string         `yaml:"format"`
-	Data   map[string]any `yaml:"data"`
+	Name    string                 `yaml:"name"`
+	Format  string                 `yaml:"format"`
+	Data    map[string]any         `yaml:"data"`
+	Timeout int                    `yaml:"timeout"`
+	Rules   []stormfetchModuleRule `yaml:"rules"`
 }
 
 type StormfetchModule struct {
-	Execute func(StormfetchModule) string
+	Execute   func(StormfetchModule) string
+	Export    func(StormfetchModule) any
+	Variables func(StormfetchModule) []map[string]string
+	List      bool
 	stormfetchModuleConfig
+
+	// Done once the module times out
+	ctx context.Context
+}
+
+type stormfetchModuleResult struct {
+	Name      string
+	Text      string
+	Value     any
+	Variables []map[string]string
+	List      bool
+	TimeTaken int64
+	TimedOut  bool
 }
 
 var Modules map[string]StormfetchModule = make(map[string]StormfetchModule)
@@ -38,281 +59,577 @@ func (sm StormfetchModule) GetData(key string, defaultValue any) (any, bool) {
 	return data, true
 }
 
+// Converts an interface slice in the module's data to an int slice
+func (sm StormfetchModule) getIntSliceData(key string) []int {
+	dataInterface, _ := sm.GetData(key, make([]any, 0))
+
+	ret := make([]int, 0)
+	for _, value := range dataInterface.([]any) {
+		if i, ok := value.(int); ok {
+			ret = append(ret, i)
+		}
+	}
+
+	return ret
+}
+
+// Converts an interface slice in the module's data to a string slice
+func (sm StormfetchModule) getStringSliceData(key string) []string {
+	dataInterface, _ := sm.GetData(key, make([]any, 0))
+
+	ret := make([]string, 0)
+	for _, value := range dataInterface.([]any) {
+		if str, ok := value.(string); ok {
+			ret = append(ret, str)
+		}
+	}
+
+	return ret
+}
+
 func RegisterModule(module StormfetchModule) bool {
 	if _, ok := Modules[module.Name]; ok {
 		return false
 	}
 
+	// Expand module format using its variables by default
+	if module.Execute == nil && module.Variables != nil {
+		module.Execute = expandModuleFormat
+	}
+
 	Modules[module.Name] = module
 	return true
 }
 
+// Expands the module's format once for every set of variables returned by the module
+func expandModuleFormat(sm StormfetchModule) string {
+	builder := strings.Builder{}
+	for _, variables := range sm.Variables(sm) {
+		// Recolor the expanded text using rules matching this item, including color codes in values such as usage bars
+		text := applyModuleRules(expandTemplate(sm.Format, variables), sm.Rules, variables)
+
+		builder.WriteString(text + "\n")
+	}
+
+	return builder.String()
+}
+
+func executeModules(moduleConfigs []stormfetchModuleConfig, outputFormat string) []stormfetchModuleResult {
+	results := make([]stormfetchModuleResult, len(moduleConfigs))
+	valid := make([]bool, len(moduleConfigs))
+
+	var wg sync.WaitGroup
+	for i, moduleConfig := range moduleConfigs {
+		module, ok := Modules[moduleConfig.Name]
+		if !ok {
+			continue
+		}
+		valid[i] = true
+
+		// Set module config options
+		if moduleConfig.Format != "" {
+			module.Format = moduleConfig.Format
+		}
+		if moduleConfig.Data != nil {
+			module.Data = moduleConfig.Data
+		}
+		module.Rules = moduleConfig.Rules
+
+		// Get module timeout
+		timeout := config.ModuleTimeout
+		if moduleConfig.Timeout != 0 {
+			timeout = moduleConfig.Timeout
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = executeModule(module, timeout, outputFormat)
+		}()
+	}
+	wg.Wait()
+
+	// Remove unknown modules
+	ret := make([]stormfetchModuleResult, 0, len(results))
+	for i, result := range results {
+		if valid[i] {
+			ret = append(ret, result)
+		}
+	}
+
+	return ret
+}
+
+func executeModule(module StormfetchModule, timeout int, outputFormat string) stormfetchModuleResult {
+	start := time.Now()
+
+	// Cancel commands started by the module once it times out or returns
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(timeout)*time.Millisecond)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	defer cancel()
+	module.ctx = ctx
+
+	output := make(chan stormfetchModuleResult, 1)
+	go func() {
+		result := stormfetchModuleResult{Name: module.Name, List: module.List}
+		switch outputFormat {
+		case OutputFormatJSON, OutputFormatYAML:
+			if module.Export != nil {
+				result.Value = module.Export(module)
+			}
+		case OutputFormatEnv:
+			if module.Variables != nil {
+				result.Variables = module.Variables(module)
+			}
+		default:
+			result.Text = module.Execute(module)
+		}
+		output <- result
+	}()
+
+	// Wait for module without a deadline if timeout is disabled
+	if timeout <= 0 {
+		result := <-output
+		result.TimeTaken = time.Since(start).Milliseconds()
+		return result
+	}
+
+	select {
+	case result := <-output:
+		result.TimeTaken = time.Since(start).Milliseconds()
+		return result
+	case <-ctx.Done():
+		// Give the module a moment to kill its commands before stormfetch exits
+		select {
+		case <-output:
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		if outputFormat != OutputFormatText {
+			return stormfetchModuleResult{Name: module.Name, TimeTaken: time.Since(start).Milliseconds(), TimedOut: true}
+		}
+
+		// Show placeholder or drop module output
+		placeholder := expandTemplate(config.ModuleTimeoutPlaceholder, map[string]string{"MODULE_NAME": module.Name})
+		return stormfetchModuleResult{Name: module.Name, Text: placeholder, TimeTaken: time.Since(start).Milliseconds(), TimedOut: true}
+	}
+}
+
 func initializeModuleMap() {
 	// Distribution Module
-	distributionModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "distribution", Format: "%3Distribution: %4$DISTRO_SHORT ($ARCH)"}, Execute: func(sm StormfetchModule) string {
+	distributionModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "distribution", Format: "%3Distribution: %4$DISTRO_SHORT ($ARCH)"}, Variables: func(sm StormfetchModule) []map[string]string {
 		distroInfo := GetDistroInfo()
-		return os.Expand(sm.Format, func(s string) string {
-			switch s {
-			case "DISTRO_ID":
-				return distroInfo.ID
-			case "DISTRO_SHORT":
-				return distroInfo.ShortName
-			case "DISTRO_LONG":
-				return distroInfo.LongName
-			case "ARCH":
-				return GetArch()
-			default:
-				return ""
-			}
-		})
+		return []map[string]string{{
+			"DISTRO_ID":               distroInfo.ID,
+			"DISTRO_SHORT":            distroInfo.ShortName,
+			"DISTRO_LONG":             distroInfo.LongName,
+			"DISTRO_ID_LIKE":          strings.Join(distroInfo.IDLike, " "),
+			"DISTRO_VERSION":          distroInfo.Version,
+			"DISTRO_VERSION_ID":       distroInfo.VersionID,
+			"DISTRO_VERSION_CODENAME": distroInfo.VersionCodename,
+			"DISTRO_BUILD_ID":         distroInfo.BuildID,
+			"DISTRO_VARIANT":          distroInfo.Variant,
+			"DISTRO_HOME_URL":         distroInfo.HomeURL,
+			"DISTRO_ANSI_COLOR":       distroInfo.AnsiColor,
+			"ARCH":                    GetArch(),
+		}}
 	}}
+	distributionModule.Export = func(sm StormfetchModule) any {
+		return struct {
+			DistroInfo `yaml:",inline"`
+			Arch       string `json:"arch" yaml:"arch"`
+		}{GetDistroInfo(), GetArch()}
+	}
 	RegisterModule(distributionModule)
 
 	// Hostname module
-	hostnameModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "hostname", Format: "%3Hostname: %4$HOSTNAME"}, Execute: func(sm StormfetchModule) string {
+	hostnameModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "hostname", Format: "%3Hostname: %4$HOSTNAME"}, Variables: func(sm StormfetchModule) []map[string]string {
 		hostname, _ := os.Hostname()
-		return os.Expand(sm.Format, func(s string) string {
-			switch s {
-			case "HOSTNAME":
-				return hostname
-			default:
-				return ""
-			}
-		})
+		return []map[string]string{{
+			"HOSTNAME": hostname,
+		}}
 	}}
+	hostnameModule.Export = func(sm StormfetchModule) any {
+		hostname, _ := os.Hostname()
+		return hostname
+	}
 	RegisterModule(hostnameModule)
 
 	// Kernel module
-	kernelModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "kernel", Format: "%3Kernel: %4$KERNEL_NAME $KERNEL_RELEASE"}, Execute: func(sm StormfetchModule) string {
+	kernelModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "kernel", Format: "%3Kernel: %4$KERNEL_NAME $KERNEL_RELEASE"}, Variables: func(sm StormfetchModule) []map[string]string {
 		kernelName, kernelRelease := GetKernel()
-		return os.Expand(sm.Format, func(s string) string {
-			switch s {
-			case "KERNEL_NAME":
-				return kernelName
-			case "KERNEL_RELEASE":
-				return kernelRelease
-			default:
-				return ""
-			}
-		})
+		return []map[string]string{{
+			"KERNEL_NAME":    kernelName,
+			"KERNEL_RELEASE": kernelRelease,
+		}}
 	}}
+	kernelModule.Export = func(sm StormfetchModule) any {
+		kernelName, kernelRelease := GetKernel()
+		return struct {
+			Name    string `json:"name" yaml:"name"`
+			Release string `json:"release" yaml:"release"`
+		}{kernelName, kernelRelease}
+	}
 	RegisterModule(kernelModule)
 
-	// Packages module
-	packagesModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "packages", Format: "%3Packages: %4$PACKAGES"}, Execute: func(sm StormfetchModule) string {
-		packages := GetInstalledPackages()
-
-		return os.Expand(sm.Format, func(s string) string {
-			switch s {
-			case "PACKAGES":
-				return packages
-			default:
-				return ""
+	// Uptime module
+	uptimeModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "uptime", Format: "%3Uptime: %4$UPTIME"}, Variables: func(sm StormfetchModule) []map[string]string {
+		uptime := GetUptime()
+		if uptime == nil {
+			return nil
+		}
+		bootTimeFormat, _ := sm.GetData("boot_time_format", "2006-01-02 15:04")
+
+		return []map[string]string{{
+			"UPTIME":         uptime.String(),
+			"UPTIME_DAYS":    strconv.Itoa(uptime.Days()),
+			"UPTIME_HOURS":   strconv.Itoa(uptime.Hours()),
+			"UPTIME_MINUTES": strconv.Itoa(uptime.Minutes()),
+			"BOOT_TIME":      uptime.BootTime.Format(bootTimeFormat.(string)),
+		}}
+	}}
+	uptimeModule.Export = func(sm StormfetchModule) any {
+		uptime := GetUptime()
+		if uptime == nil {
+			return nil
+		}
+
+		return struct {
+			Seconds  int64     `json:"seconds" yaml:"seconds"`
+			Days     int       `json:"days" yaml:"days"`
+			Hours    int       `json:"hours" yaml:"hours"`
+			Minutes  int       `json:"minutes" yaml:"minutes"`
+			BootTime time.Time `json:"boot_time" yaml:"boot_time"`
+		}{int64(uptime.Duration.Seconds()), uptime.Days(), uptime.Hours(), uptime.Minutes(), uptime.BootTime}
+	}
+	RegisterModule(uptimeModule)
+
+	// Load module
+	loadModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "load", Format: "%3Load: %4$LOAD_1, $LOAD_5, $LOAD_15 ($PROCESSES processes)"}, Variables: func(sm StormfetchModule) []map[string]string {
+		loadAverage := GetLoadAverage()
+		if loadAverage == nil {
+			return nil
+		}
+
+		variables := map[string]string{
+			"LOAD_1":       strconv.FormatFloat(loadAverage.Load1, 'f', 2, 64),
+			"LOAD_5":       strconv.FormatFloat(loadAverage.Load5, 'f', 2, 64),
+			"LOAD_15":      strconv.FormatFloat(loadAverage.Load15, 'f', 2, 64),
+			"LOAD_RUNNING": strconv.Itoa(loadAverage.RunningTasks),
+			"LOAD_TASKS":   strconv.Itoa(loadAverage.TotalTasks),
+			"PROCESSES":    strconv.Itoa(loadAverage.Processes),
+		}
+
+		// Scale load by CPU thread count
+		if showPercentage, _ := sm.GetData("show_percentage", false); showPercentage.(bool) {
+			for i, percentage := range loadAverage.Percentages() {
+				variables["LOAD_"+[]string{"1", "5", "15"}[i]+"_PERCENT"] = strconv.FormatFloat(percentage, 'f', 0, 64)
 			}
-		})
+		}
+
+		return []map[string]string{variables}
+	}}
+	loadModule.Export = func(sm StormfetchModule) any {
+		loadAverage := GetLoadAverage()
+		if loadAverage == nil {
+			return nil
+		}
+
+		if showPercentage, _ := sm.GetData("show_percentage", false); showPercentage.(bool) {
+			return struct {
+				*LoadAverage `yaml:",inline"`
+				Percentages  []float64 `json:"percentages" yaml:"percentages"`
+			}{loadAverage, loadAverage.Percentages()}
+		}
+		return loadAverage
+	}
+	RegisterModule(loadModule)
+
+	// Packages module
+	packagesModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "packages", Format: "%3Packages: %4$PACKAGES"}, Variables: func(sm StormfetchModule) []map[string]string {
+		return []map[string]string{{
+			"PACKAGES": GetInstalledPackages(),
+		}}
 	}}
+	packagesModule.Export = func(sm StormfetchModule) any {
+		return GetPackageCounts()
+	}
 	RegisterModule(packagesModule)
 
 	// Shell module
-	shellModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "shell", Format: "%3Shell: %4$SHELL"}, Execute: func(sm StormfetchModule) string {
-		shell := GetShell()
-
-		return os.Expand(sm.Format, func(s string) string {
-			switch s {
-			case "SHELL":
-				return shell
-			default:
-				return ""
-			}
-		})
+	shellModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "shell", Format: "%3Shell: %4$SHELL"}, Variables: func(sm StormfetchModule) []map[string]string {
+		return []map[string]string{{
+			"SHELL": GetShell(),
+		}}
 	}}
+	shellModule.Export = func(sm StormfetchModule) any {
+		return GetShell()
+	}
 	RegisterModule(shellModule)
 
 	// Init system module
-	initSystemModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "init_system", Format: "%3Init: %4$INIT"}, Execute: func(sm StormfetchModule) string {
+	initSystemModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "init_system", Format: "%3Init: %4$INIT"}, Variables: func(sm StormfetchModule) []map[string]string {
 		initSystem := GetInitSystem()
 
 		if initSystem == "" {
-			return ""
+			return nil
 		}
 
-		return os.Expand(sm.Format, func(s string) string {
-			switch s {
-			case "INIT":
-				return initSystem
-			default:
-				return ""
-			}
-		})
+		return []map[string]string{{
+			"INIT": initSystem,
+		}}
 	}}
+	initSystemModule.Export = func(sm StormfetchModule) any {
+		if initSystem := GetInitSystem(); initSystem != "" {
+			return initSystem
+		}
+		return nil
+	}
 	RegisterModule(initSystemModule)
 
 	// Libc module
-	libcModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "libc", Format: "%3Libc: %4$LIBC"}, Execute: func(sm StormfetchModule) string {
-		libc := GetLibc()
-
-		return os.Expand(sm.Format, func(s string) string {
-			switch s {
-			case "LIBC":
-				return libc
-			default:
-				return ""
-			}
-		})
+	libcModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "libc", Format: "%3Libc: %4$LIBC"}, Variables: func(sm StormfetchModule) []map[string]string {
+		return []map[string]string{{
+			"LIBC": GetLibc(),
+		}}
 	}}
+	libcModule.Export = func(sm StormfetchModule) any {
+		return GetLibc()
+	}
 	RegisterModule(libcModule)
 
+	// Virtualization module
+	virtualizationModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "virtualization", Format: "%3Virtualization: %4$VIRT_NAME ($VIRT_TYPE)"}, Variables: func(sm StormfetchModule) []map[string]string {
+		virtualization := GetVirtualization()
+
+		// Return no variables when running on bare metal
+		if virtualization == nil {
+			return nil
+		}
+
+		return []map[string]string{{
+			"VIRT_TYPE": virtualization.Type,
+			"VIRT_NAME": virtualization.Name,
+		}}
+	}}
+	virtualizationModule.Export = func(sm StormfetchModule) any {
+		if virtualization := GetVirtualization(); virtualization != nil {
+			return virtualization
+		}
+		return nil
+	}
+	RegisterModule(virtualizationModule)
+
 	// Motherboard module
-	MotherboardModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "motherboard", Format: "%3Motherboard: %4$MOTHERBOARD"}, Execute: func(sm StormfetchModule) string {
+	MotherboardModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "motherboard", Format: "%3Motherboard: %4$MOTHERBOARD"}, Variables: func(sm StormfetchModule) []map[string]string {
 		motherboard := GetMotherboardModel()
 
-		// Return empty string if can't detect motherboard model
+		// Return no variables if can't detect motherboard model
 		if motherboard == "" {
-			return ""
+			return nil
 		}
 
-		return os.Expand(sm.Format, func(s string) string {
-			switch s {
-			case "MOTHERBOARD":
-				return motherboard
-			default:
-				return ""
-			}
-		})
+		return []map[string]string{{
+			"MOTHERBOARD": motherboard,
+		}}
 	}}
+	MotherboardModule.Export = func(sm StormfetchModule) any {
+		if motherboard := GetMotherboardModel(); motherboard != "" {
+			return motherboard
+		}
+		return nil
+	}
 	RegisterModule(MotherboardModule)
 
-	// Motherboard module
-	cpusModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "cpus", Format: "%3CPU: %4$CPU_MODEL ($CPU_THREADS threads)"}, Execute: func(sm StormfetchModule) string {
-		hiddenCPUsInterface, _ := sm.GetData("hidden_cpus", make([]any, 0))
-
-		// Convert interface slices to string slices
-		hiddenCPUs := make([]int, 0)
-		for _, value := range hiddenCPUsInterface.([]any) {
-			hiddenCPUs = append(hiddenCPUs, value.(int))
-		}
-
-		builder := strings.Builder{}
-		cpus := GetCPUs(hiddenCPUs)
-
-		for i, cpu := range cpus {
-			expanded := os.Expand(sm.Format, func(s string) string {
-				switch s {
-				case "CPU_NUM":
-					return strconv.Itoa(i + 1)
-				case "CPU_MODEL":
-					return cpu.Model
-				case "CPU_CORES":
-					return strconv.Itoa(cpu.Cores)
-				case "CPU_THREADS":
-					return strconv.Itoa(cpu.Threads)
-				default:
-					return ""
-				}
+	// CPUs module
+	cpusModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "cpus", Format: "%3CPU: %4$CPU_MODEL ($CPU_THREADS threads)"}, List: true, Variables: func(sm StormfetchModule) []map[string]string {
+		variables := make([]map[string]string, 0)
+		for i, cpu := range GetCPUs(sm.getIntSliceData("hidden_cpus")) {
+			variables = append(variables, map[string]string{
+				"CPU_NUM":     strconv.Itoa(i + 1),
+				"CPU_MODEL":   cpu.Model,
+				"CPU_CORES":   strconv.Itoa(cpu.Cores),
+				"CPU_THREADS": strconv.Itoa(cpu.Threads),
 			})
-
-			builder.WriteString(expanded + "\n")
 		}
 
-		return builder.String()
+		return variables
 	}}
+	cpusModule.Export = func(sm StormfetchModule) any {
+		return GetCPUs(sm.getIntSliceData("hidden_cpus"))
+	}
 	RegisterModule(cpusModule)
 
 	// GPUs module
-	gpusModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "gpus", Format: "%3GPU: %4$GPU_VENDOR $GPU_NAME"}, Execute: func(sm StormfetchModule) string {
-		hiddenGPUsInterface, _ := sm.GetData("hidden_gpus", make([]any, 0))
-
-		// Convert interface slices to string slices
-		hiddenGPUs := make([]int, 0)
-		for _, value := range hiddenGPUsInterface.([]any) {
-			hiddenGPUs = append(hiddenGPUs, value.(int))
-		}
-
-		builder := strings.Builder{}
-		gpus := GetGPUModels(hiddenGPUs)
-
-		for i, gpu := range gpus {
-			expanded := os.Expand(sm.Format, func(s string) string {
-				switch s {
-				case "GPU_NUM":
-					return strconv.Itoa(i + 1)
-				case "GPU_VENDOR":
-					return gpu.Vendor
-				case "GPU_NAME":
-					return gpu.Name
-				case "GPU_PRODUCT":
-					return gpu.Product
-				case "GPU_SUBSYSTEM":
-					return gpu.Subsystem
-				case "GPU_DRIVER":
-					return gpu.Driver
-				case "GPU_VRAM_TOTAL":
-					return gpu.VramTotal
-				case "GPU_VRAM_USED":
-					return gpu.VramUsed
-				default:
-					return ""
-				}
-			})
+	gpusModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "gpus", Format: "%3GPU: %4$GPU_VENDOR $GPU_NAME"}, List: true, Variables: func(sm StormfetchModule) []map[string]string {
+		bar := sm.getUsageBar()
+
+		variables := make([]map[string]string, 0)
+		for i, gpu := range GetGPUModels(sm.getIntSliceData("hidden_gpus")) {
+			// Only show VRAM usage if it's known
+			vramPercent, vramBar := "", ""
+			if gpu.VramTotalBytes > 0 {
+				percent := usagePercent(float64(gpu.VramUsedBytes), float64(gpu.VramTotalBytes))
+				vramPercent, vramBar = formatPercent(percent), bar.Render(percent)
+			}
 
-			builder.WriteString(expanded + "\n")
+			variables = append(variables, map[string]string{
+				"GPU_NUM":          strconv.Itoa(i + 1),
+				"GPU_VENDOR":       gpu.Vendor,
+				"GPU_NAME":         gpu.Name,
+				"GPU_PRODUCT":      gpu.Product,
+				"GPU_SUBSYSTEM":    gpu.Subsystem,
+				"GPU_DRIVER":       gpu.Driver,
+				"GPU_VRAM_TOTAL":   gpu.VramTotal,
+				"GPU_VRAM_USED":    gpu.VramUsed,
+				"GPU_VRAM_PERCENT": vramPercent,
+				"GPU_VRAM_BAR":     vramBar,
+			})
 		}
 
-		return builder.String()
+		return variables
 	}}
+	gpusModule.Export = func(sm StormfetchModule) any {
+		return GetGPUModels(sm.getIntSliceData("hidden_gpus"))
+	}
 	RegisterModule(gpusModule)
 
 	// Memory module
-	memoryModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "memory", Format: "%3Memory: %4$MEM_USED MiB / $MEM_TOTAL MiB"}, Execute: func(sm StormfetchModule) string {
+	memoryModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "memory", Format: "%3Memory: %4$MEM_USED{{if MEM_UNIT}} $MEM_UNIT{{end}} / $MEM_TOTAL{{if MEM_UNIT}} $MEM_UNIT{{end}}"}, Variables: func(sm StormfetchModule) []map[string]string {
 		memoryInfo := GetMemoryInfo()
 
 		if memoryInfo == nil {
-			return ""
-		}
-
-		return os.Expand(sm.Format, func(s string) string {
-			switch s {
-			case "MEM_TOTAL":
-				return strconv.Itoa(memoryInfo.MemTotal)
-			case "MEM_AVAILABLE":
-				return strconv.Itoa(memoryInfo.MemAvailable)
-			case "MEM_FREE":
-				return strconv.Itoa(memoryInfo.MemFree)
-			case "MEM_USED":
-				return strconv.Itoa(memoryInfo.MemTotal - memoryInfo.MemAvailable)
-			default:
-				return ""
-			}
-		})
+			return nil
+		}
+
+		unit, _ := sm.GetData("unit", MemoryUnitMiB)
+		usedFormula, _ := sm.GetData("used_formula", UsedMemoryFormulaAvailable)
+		formatSize := func(kib int) string {
+			return FormatMemorySize(kib, unit.(string))
+		}
+
+		// Sum up zram devices
+		zramTotal := ZramDevice{}
+		for _, zram := range memoryInfo.Zram {
+			zramTotal.OriginalSize += zram.OriginalSize
+			zramTotal.CompressedSize += zram.CompressedSize
+		}
+
+		bar := sm.getUsageBar()
+		memPercent := usagePercent(float64(memoryInfo.MemUsed(usedFormula.(string))), float64(memoryInfo.MemTotal))
+		swapPercent := usagePercent(float64(memoryInfo.SwapUsed()), float64(memoryInfo.SwapTotal))
+
+		return []map[string]string{{
+			"MEM_UNIT":            MemoryUnitLabel(unit.(string)),
+			"MEM_TOTAL":           formatSize(memoryInfo.MemTotal),
+			"MEM_AVAILABLE":       formatSize(memoryInfo.MemAvailable),
+			"MEM_FREE":            formatSize(memoryInfo.MemFree),
+			"MEM_USED":            formatSize(memoryInfo.MemUsed(usedFormula.(string))),
+			"MEM_BUFFERS":         formatSize(memoryInfo.Buffers),
+			"MEM_CACHED":          formatSize(memoryInfo.Cached),
+			"MEM_SHMEM":           formatSize(memoryInfo.Shmem),
+			"MEM_SRECLAIMABLE":    formatSize(memoryInfo.SReclaimable),
+			"MEM_DIRTY":           formatSize(memoryInfo.Dirty),
+			"MEM_HUGEPAGES_TOTAL": strconv.Itoa(memoryInfo.HugePagesTotal),
+			"MEM_PERCENT":         formatPercent(memPercent),
+			"MEM_BAR":             bar.Render(memPercent),
+			"SWAP_UNIT":           MemoryUnitLabel(unit.(string)),
+			"SWAP_TOTAL":          formatSize(memoryInfo.SwapTotal),
+			"SWAP_FREE":           formatSize(memoryInfo.SwapFree),
+			"SWAP_USED":           formatSize(memoryInfo.SwapUsed()),
+			"SWAP_DEVICES":        strconv.Itoa(len(memoryInfo.SwapDevices)),
+			"SWAP_PERCENT":        formatPercent(swapPercent),
+			"SWAP_BAR":            bar.Render(swapPercent),
+			"ZRAM_ORIGINAL":       formatSize(zramTotal.OriginalSize),
+			"ZRAM_COMPRESSED":     formatSize(zramTotal.CompressedSize),
+			"ZRAM_RATIO":          strconv.FormatFloat(zramTotal.CompressionRatio(), 'f', 2, 64),
+		}}
 	}}
+	memoryModule.Export = func(sm StormfetchModule) any {
+		if memoryInfo := GetMemoryInfo(); memoryInfo != nil {
+			return memoryInfo
+		}
+		return nil
+	}
 	RegisterModule(memoryModule)
 
-	// Partitions module
-	partitionsModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "partitions", Format: "%3Partition ${PART_AUTONAME} (${PART_FS}): %4${PART_USED} / ${PART_TOTAL}"}, Execute: func(sm StormfetchModule) string {
-		hiddenPartitionsInterface, _ := sm.GetData("hidden_partitions", make([]any, 0))
-		hiddenFilesystemsInterface, _ := sm.GetData("hidden_filesystems", make([]any, 0))
-		alternativeNamesInterface, _ := sm.GetData("alternative_names", make(map[string]any, 0))
+	// Swap module
+	swapModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "swap", Format: "%3Swap ($SWAP_NAME): %4$SWAP_USED{{if SWAP_UNIT}} $SWAP_UNIT{{end}} / $SWAP_SIZE{{if SWAP_UNIT}} $SWAP_UNIT{{end}}"}, List: true, Variables: func(sm StormfetchModule) []map[string]string {
+		memoryInfo := GetMemoryInfo()
 
-		// Convert interface slices to string slices
-		hiddenPartitions := make([]string, 0)
-		for _, value := range hiddenPartitionsInterface.([]any) {
-			hiddenPartitions = append(hiddenPartitions, value.(string))
+		// Return no variables if no swap is configured
+		if memoryInfo == nil || memoryInfo.SwapTotal == 0 {
+			return nil
 		}
-		hiddenFilesystems := make([]string, 0)
-		for _, value := range hiddenFilesystemsInterface.([]any) {
-			hiddenFilesystems = append(hiddenFilesystems, value.(string))
+
+		unit, _ := sm.GetData("unit", MemoryUnitMiB)
+		formatSize := func(kib int) string {
+			return FormatMemorySize(kib, unit.(string))
 		}
 
+		bar := sm.getUsageBar()
+
+		variables := make([]map[string]string, 0)
+		for i, swapDevice := range memoryInfo.SwapDevices {
+			swapPercent := usagePercent(float64(swapDevice.Used), float64(swapDevice.Size))
+			swapVariables := map[string]string{
+				"SWAP_NUM":        strconv.Itoa(i + 1),
+				"SWAP_NAME":       swapDevice.Name,
+				"SWAP_UNIT":       MemoryUnitLabel(unit.(string)),
+				"SWAP_TYPE":       swapDevice.Type,
+				"SWAP_SIZE":       formatSize(swapDevice.Size),
+				"SWAP_USED":       formatSize(swapDevice.Used),
+				"SWAP_PRIORITY":   strconv.Itoa(swapDevice.Priority),
+				"SWAP_PERCENT":    formatPercent(swapPercent),
+				"SWAP_BAR":        bar.Render(swapPercent),
+				"ZRAM_ORIGINAL":   "",
+				"ZRAM_COMPRESSED": "",
+				"ZRAM_RATIO":      "",
+			}
+
+			// Add compression stats for zram devices
+			for _, zram := range memoryInfo.Zram {
+				if swapDevice.Name == "/dev/"+zram.Name {
+					swapVariables["ZRAM_ORIGINAL"] = formatSize(zram.OriginalSize)
+					swapVariables["ZRAM_COMPRESSED"] = formatSize(zram.CompressedSize)
+					swapVariables["ZRAM_RATIO"] = strconv.FormatFloat(zram.CompressionRatio(), 'f', 2, 64)
+				}
+			}
+
+			variables = append(variables, swapVariables)
+		}
+
+		return variables
+	}}
+	swapModule.Export = func(sm StormfetchModule) any {
+		if memoryInfo := GetMemoryInfo(); memoryInfo != nil && memoryInfo.SwapTotal != 0 {
+			return memoryInfo.SwapDevices
+		}
+		return nil
+	}
+	RegisterModule(swapModule)
+
+	// Partitions module
+	partitionsModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "partitions", Format: "%3Partition ${PART_AUTONAME} (${PART_FS}): %4${PART_USED} / ${PART_TOTAL}"}, List: true, Variables: func(sm StormfetchModule) []map[string]string {
+		hiddenPartitions := sm.getStringSliceData("hidden_partitions")
+		hiddenFilesystems := sm.getStringSliceData("hidden_filesystems")
+		alternativeNamesInterface, _ := sm.GetData("alternative_names", make(map[string]any, 0))
+
 		// Convert interface map to map[string]string
 		alternativeNames := make(map[string]string)
 		for key, value := range alternativeNamesInterface.(map[string]any) {
 			alternativeNames[key] = value.(string)
 		}
 
-		builder := strings.Builder{}
-		partitions := GetMountedPartitions(hiddenPartitions, hiddenFilesystems)
+		bar := sm.getUsageBar()
 
-		for i, partition := range partitions {
+		variables := make([]map[string]string, 0)
+		for i, partition := range GetMountedPartitions(hiddenPartitions, hiddenFilesystems) {
 			partitionAutoname := ""
 			if altName, ok := alternativeNames[partition.Device]; ok {
 				partitionAutoname = altName
@@ -322,146 +639,128 @@ func initializeModuleMap() {
 				partitionAutoname = partition.MountPoint
 			}
 
-			expanded := os.Expand(sm.Format, func(s string) string {
-				switch s {
-				case "PART_NUM":
-					return strconv.Itoa(i + 1)
-				case "PART_FS":
-					return partition.FileystemType
-				case "PART_DEVICE":
-					return partition.Device
-				case "PART_AUTONAME":
-					return partitionAutoname
-				case "PART_LABEL":
-					return partition.Label
-				case "PART_MOUNTPOINT":
-					return partition.MountPoint
-				case "PART_FREE":
-					return FormatBytes(partition.FreeSize)
-				case "PART_USED":
-					return FormatBytes(partition.UsedSize)
-				case "PART_TOTAL":
-					return FormatBytes(partition.TotalSize)
-				default:
-					return ""
-				}
+			partitionPercent := usagePercent(float64(partition.UsedSize), float64(partition.TotalSize))
+			variables = append(variables, map[string]string{
+				"PART_NUM":        strconv.Itoa(i + 1),
+				"PART_FS":         partition.FileystemType,
+				"PART_DEVICE":     partition.Device,
+				"PART_AUTONAME":   partitionAutoname,
+				"PART_LABEL":      partition.Label,
+				"PART_MOUNTPOINT": partition.MountPoint,
+				"PART_FREE":       FormatBytes(partition.FreeSize),
+				"PART_USED":       FormatBytes(partition.UsedSize),
+				"PART_TOTAL":      FormatBytes(partition.TotalSize),
+				"PART_PERCENT":    formatPercent(partitionPercent),
+				"PART_BAR":        bar.Render(partitionPercent),
 			})
-
-			builder.WriteString(expanded + "\n")
 		}
 
-		return builder.String()
+		return variables
 	}}
+	partitionsModule.Export = func(sm StormfetchModule) any {
+		return GetMountedPartitions(sm.getStringSliceData("hidden_partitions"), sm.getStringSliceData("hidden_filesystems"))
+	}
 	RegisterModule(partitionsModule)
 
 	// Local IP module
-	localIpModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "local_ip", Format: "%3Local IP: %4$LOCAL_IP"}, Execute: func(sm StormfetchModule) string {
+	localIpModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "local_ip", Format: "%3Local IP: %4$LOCAL_IP"}, Variables: func(sm StormfetchModule) []map[string]string {
 		localIP := GetLocalIP()
 
-		// Return empty string if local IP is unavailable
+		// Return no variables if local IP is unavailable
 		if localIP == "Unknown" {
-			return ""
+			return nil
 		}
 
-		return os.Expand(sm.Format, func(s string) string {
-			switch s {
-			case "LOCAL_IP":
-				return localIP
-			default:
-				return ""
-			}
-		})
+		return []map[string]string{{
+			"LOCAL_IP": localIP,
+		}}
 	}}
+	localIpModule.Export = func(sm StormfetchModule) any {
+		if localIP := GetLocalIP(); localIP != "Unknown" {
+			return localIP
+		}
+		return nil
+	}
 	RegisterModule(localIpModule)
 
 	// DEWM module
-	dewmModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "de_wm", Format: "%3${DEWM_TYPE}: %4${DEWM_NAME} ($DISPLAY_PROTOCOL)"}, Execute: func(sm StormfetchModule) string {
-		// Return empty string if currently in TTY
-		if os.Getenv("XDG_SESSION_TYPE") == "" || os.Getenv("XDG_SESSION_TYPE") == "tty" {
-			return ""
+	dewmModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "de_wm", Format: "%3${DEWM_TYPE}: %4${DEWM_NAME} ($DISPLAY_PROTOCOL)"}, Variables: func(sm StormfetchModule) []map[string]string {
+		// Return no variables if currently in TTY
+		if sessionType := getSessionType(); sessionType == "" || sessionType == "tty" {
+			return nil
 		}
 
 		dewm := GetDEWM()
 		displayProtocol := GetDisplayProtocol()
 
-		// Return empty string if can't detect DE/WM
+		// Return no variables if can't detect DE/WM
 		if dewm.Name == "Unknown" {
-			return ""
-		}
-
-		return os.Expand(sm.Format, func(s string) string {
-			switch s {
-			case "DEWM_NAME":
-				return dewm.Name
-			case "DEWM_TYPE":
-				return dewm.Type
-			case "DISPLAY_PROTOCOL":
-				return displayProtocol
-			default:
-				return ""
-			}
-		})
+			return nil
+		}
+
+		return []map[string]string{{
+			"DEWM_NAME":        dewm.Name,
+			"DEWM_TYPE":        dewm.Type,
+			"DISPLAY_PROTOCOL": displayProtocol,
+		}}
 	}}
+	dewmModule.Export = func(sm StormfetchModule) any {
+		if sessionType := getSessionType(); sessionType == "" || sessionType == "tty" {
+			return nil
+		}
+
+		dewm := GetDEWM()
+		if dewm.Name == "Unknown" {
+			return nil
+		}
+
+		return struct {
+			DEWM            `yaml:",inline"`
+			DisplayProtocol string `json:"display_protocol" yaml:"display_protocol"`
+		}{dewm, GetDisplayProtocol()}
+	}
 	RegisterModule(dewmModule)
 
 	// Monitors module
-	monitorsModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "monitors", Format: "%3Monitor ${MONITOR_NAME}: %4${MONITOR_WIDTH}x${MONITOR_HEIGHT} ${MONITOR_REFRESH_RATE}Hz"}, Execute: func(sm StormfetchModule) string {
-		builder := strings.Builder{}
-		monitors := GetMonitors()
-
-		for _, monitor := range monitors {
-			expanded := os.Expand(sm.Format, func(s string) string {
-				switch s {
-				case "MONITOR_NAME":
-					return monitor.Name
-				case "MONITOR_WIDTH":
-					return strconv.Itoa(monitor.Width)
-				case "MONITOR_HEIGHT":
-					return strconv.Itoa(monitor.Height)
-				case "MONITOR_REFRESH_RATE":
-					return strconv.Itoa(monitor.RefreshRate)
-				default:
-					return ""
-				}
+	monitorsModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "monitors", Format: "%3Monitor ${MONITOR_NAME}: %4${MONITOR_WIDTH}x${MONITOR_HEIGHT} ${MONITOR_REFRESH_RATE}Hz"}, List: true, Variables: func(sm StormfetchModule) []map[string]string {
+		variables := make([]map[string]string, 0)
+		for _, monitor := range GetMonitors() {
+			variables = append(variables, map[string]string{
+				"MONITOR_NAME":         monitor.Name,
+				"MONITOR_WIDTH":        strconv.Itoa(monitor.Width),
+				"MONITOR_HEIGHT":       strconv.Itoa(monitor.Height),
+				"MONITOR_REFRESH_RATE": strconv.Itoa(monitor.RefreshRate),
 			})
-
-			builder.WriteString(expanded + "\n")
 		}
 
-		return builder.String()
+		return variables
 	}}
+	monitorsModule.Export = func(sm StormfetchModule) any {
+		return GetMonitors()
+	}
 	RegisterModule(monitorsModule)
 
 	// Custom module
-	customModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "custom"}, Execute: func(sm StormfetchModule) string {
+	customModule := StormfetchModule{stormfetchModuleConfig: stormfetchModuleConfig{Name: "custom"}, Variables: func(sm StormfetchModule) []map[string]string {
 		shell, _ := sm.GetData("shell", "/bin/sh")
-		commandList, _ := sm.GetData("commands", make([]any, 0))
 
 		// Exeucte all commands
-		commandOutput := make(map[int]string)
-		for i, value := range commandList.([]any) {
-			command, ok := value.(string)
-			if !ok {
-				continue
-			}
-
-			commandOutput[i+1] = runCommand(command, shell.(string))
+		variables := make(map[string]string)
+		for i, command := range sm.getStringSliceData("commands") {
+			variables["CMD_"+strconv.Itoa(i+1)] = runCommandContext(sm.ctx, command, shell.(string))
 		}
 
-		return os.Expand(sm.Format, func(s string) string {
-			if len(s) <= 4 || !strings.HasPrefix(s, "CMD_") {
-				return ""
-			}
-
-			commandIndexStr := strings.Split(s, "CMD_")[1]
+		return []map[string]string{variables}
+	}}
+	customModule.Export = func(sm StormfetchModule) any {
+		shell, _ := sm.GetData("shell", "/bin/sh")
 
-			commandIndex, err := strconv.Atoi(commandIndexStr)
-			if err != nil {
-				return ""
-			}
+		commandOutput := make([]string, 0)
+		for _, command := range sm.getStringSliceData("commands") {
+			commandOutput = append(commandOutput, runCommandContext(sm.ctx, command, shell.(string)))
+		}
 
-			return commandOutput[commandIndex]
-		})
-	}}
+		return commandOutput
+	}
 	RegisterModule(customModule)
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputFormatText = "text"
+	OutputFormatJSON = "json"
+	OutputFormatYAML = "yaml"
+	OutputFormatEnv  = "env"
+)
+
+var OutputFormats = []string{OutputFormatText, OutputFormatJSON, OutputFormatYAML, OutputFormatEnv}
+
+type structuredModuleOutput struct {
+	Name string `json:"name" yaml:"name"`
+	Data any    `json:"data" yaml:"data"`
+}
+
+type structuredOutput struct {
+	Version string                   `json:"version" yaml:"version"`
+	Modules []structuredModuleOutput `json:"modules" yaml:"modules"`
+}
+
+func executeModulesQuietly(outputFormat string) []stormfetchModuleResult {
+	results := executeModules(config.Modules, outputFormat)
+
+	// Show time taken on stderr to keep output parsable
+	if ShowModuleTimeTaken {
+		for _, result := range results {
+			if result.TimedOut {
+				fmt.Fprintf(os.Stderr, "Module '%s' timed out after %d milliseconds\n", result.Name, result.TimeTaken)
+			} else {
+				fmt.Fprintf(os.Stderr, "Module '%s' took %d milliseconds\n", result.Name, result.TimeTaken)
+			}
+		}
+	}
+
+	return results
+}
+
+func getStructuredOutput(outputFormat string) structuredOutput {
+	output := structuredOutput{
+		Version: StormfetchVersion,
+		Modules: make([]structuredModuleOutput, 0),
+	}
+
+	for _, result := range executeModulesQuietly(outputFormat) {
+		// Skip modules with no data
+		if result.Value == nil {
+			continue
+		}
+
+		output.Modules = append(output.Modules, structuredModuleOutput{
+			Name: result.Name,
+			Data: result.Value,
+		})
+	}
+
+	return output
+}
+
+func printJSON() {
+	bytes, err := json.MarshalIndent(getStructuredOutput(OutputFormatJSON), "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(string(bytes))
+}
+
+func printYAML() {
+	bytes, err := yaml.Marshal(getStructuredOutput(OutputFormatYAML))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Print(string(bytes))
+}
+
+// Variable name prefixes must be valid shell variable names
+var envPrefixRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Prints module variables as shell assignments. Names are prefixed with STORMFETCH_ by default, since names such as
+// SHELL or HOSTNAME would otherwise overwrite shell variables when the output is evaluated
+func printEnv() {
+	builder := strings.Builder{}
+	for _, result := range executeModulesQuietly(OutputFormatEnv) {
+		for i, variables := range result.Variables {
+			// Sort variable names for stable output
+			keys := make([]string, 0, len(variables))
+			for key := range variables {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+
+			for _, key := range keys {
+				// Number variables of modules that return a list of items
+				name := EnvPrefix + key
+				if result.List {
+					name += "_" + strconv.Itoa(i+1)
+				}
+
+				// Strip color codes used by values such as usage bars
+				builder.WriteString(name + "=" + shellQuote(removeColorCodes(variables[key])) + "\n")
+			}
+		}
+	}
+
+	fmt.Print(builder.String())
+}
+
+// Quotes a string so it can be safely evaluated by a POSIX shell
+func shellQuote(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", `'\''`) + "'"
+}
@@ -9,35 +9,35 @@ import (
 )
 
 type partition struct {
-	Device        string
-	MountPoint    string
-	Label         string
-	FileystemType string
-	TotalSize     uint64
-	UsedSize      uint64
-	FreeSize      uint64
+	Device        string `json:"device" yaml:"device"`
+	MountPoint    string `json:"mount_point" yaml:"mount_point"`
+	Label         string `json:"label" yaml:"label"`
+	FileystemType string `json:"filesystem_type" yaml:"filesystem_type"`
+	TotalSize     uint64 `json:"total_size" yaml:"total_size"`
+	UsedSize      uint64 `json:"used_size" yaml:"used_size"`
+	FreeSize      uint64 `json:"free_size" yaml:"free_size"`
 }
 
 func GetMountedPartitions(hiddenPartitions, hiddenFilesystems []string) []partition {
 	// Get all filesystem and partition labels
-	fslabels, err := os.ReadDir("/dev/disk/by-label")
+	fslabels, err := SystemFS.ReadDir("/dev/disk/by-label")
 	if err != nil && !os.IsNotExist(err) {
 		return nil
 	}
-	partlabels, err := os.ReadDir("/dev/disk/by-partlabel")
+	partlabels, err := SystemFS.ReadDir("/dev/disk/by-partlabel")
 	if err != nil && !os.IsNotExist(err) {
 		return nil
 	}
 	labels := make(map[string]string)
 	for _, entry := range partlabels {
-		link, err := filepath.EvalSymlinks(filepath.Join("/dev/disk/by-partlabel/", entry.Name()))
+		link, err := SystemFS.EvalSymlinks(filepath.Join("/dev/disk/by-partlabel/", entry.Name()))
 		if err != nil {
 			continue
 		}
 		labels[link] = entry.Name()
 	}
 	for _, entry := range fslabels {
-		link, err := filepath.EvalSymlinks(filepath.Join("/dev/disk/by-label/", entry.Name()))
+		link, err := SystemFS.EvalSymlinks(filepath.Join("/dev/disk/by-label/", entry.Name()))
 		if err != nil {
 			continue
 		}
@@ -45,7 +45,7 @@ func GetMountedPartitions(hiddenPartitions, hiddenFilesystems []string) []partit
 	}
 
 	// Get all mounted partitions
-	file, err := os.ReadFile("/proc/mounts")
+	file, err := SystemFS.ReadFile("/proc/mounts")
 	if err != nil {
 		return nil
 	}
@@ -72,7 +72,7 @@ func GetMountedPartitions(hiddenPartitions, hiddenFilesystems []string) []partit
 			continue
 		}
 
-		device, err := filepath.EvalSymlinks(fields[0])
+		device, err := SystemFS.EvalSymlinks(fields[0])
 		if err != nil {
 			device = fields[0]
 		}
@@ -105,7 +105,7 @@ func GetMountedPartitions(hiddenPartitions, hiddenFilesystems []string) []partit
 
 		// Get partition total, used and free space
 		buf := new(syscall.Statfs_t)
-		err = syscall.Statfs(p.MountPoint, buf)
+		err = SystemFS.Statfs(p.MountPoint, buf)
 		if err != nil {
 			continue
 		}
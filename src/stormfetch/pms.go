@@ -2,8 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
 	"path"
 	"strconv"
 	"strings"
@@ -29,22 +27,36 @@ var PackageManagers = []PackageManager{
 
 func (pm *PackageManager) CountPackages() int {
 	// Return 0 if package manager is not found
-	if _, err := exec.LookPath(pm.ExecutableName); err != nil {
+	if _, err := SystemFS.LookPath(pm.ExecutableName); err != nil {
 		return 0
 	}
 
 	return pm.GetPackages(pm.FunctionInput...)
 }
 
-func GetInstalledPackages() (ret string) {
+type PackageCount struct {
+	Manager string `json:"manager" yaml:"manager"`
+	Count   int    `json:"count" yaml:"count"`
+}
+
+func GetPackageCounts() []PackageCount {
+	ret := make([]PackageCount, 0)
 	for _, pm := range PackageManagers {
 		count := pm.CountPackages()
 		if count > 0 {
-			if ret == "" {
-				ret += fmt.Sprintf("%d (%s)", count, pm.Name)
-			} else {
-				ret += fmt.Sprintf(" %d (%s)", count, pm.Name)
-			}
+			ret = append(ret, PackageCount{Manager: pm.Name, Count: count})
+		}
+	}
+
+	return ret
+}
+
+func GetInstalledPackages() (ret string) {
+	for _, packageCount := range GetPackageCounts() {
+		if ret == "" {
+			ret += fmt.Sprintf("%d (%s)", packageCount.Count, packageCount.Manager)
+		} else {
+			ret += fmt.Sprintf(" %d (%s)", packageCount.Count, packageCount.Manager)
 		}
 	}
 
@@ -59,7 +71,7 @@ func pmDirectoryElements(args ...any) int {
 	}
 	total := 0
 
-	dirEntries, _ := os.ReadDir(directory)
+	dirEntries, _ := SystemFS.ReadDir(directory)
 	for _, entry := range dirEntries {
 		if !entry.IsDir() && dirsOnly {
 			continue
@@ -78,7 +90,7 @@ func pmFileLines(args ...any) int {
 	}
 	total := 0
 
-	content, err := os.ReadFile(filepath)
+	content, err := SystemFS.ReadFile(filepath)
 	if err == nil {
 		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
 			if mustContain != "" && !strings.Contains(line, mustContain) {
@@ -113,13 +125,13 @@ func pmPortage(args ...any) int {
 	portageDir := "/var/db/pkg"
 	total := 0
 
-	dirEntries, _ := os.ReadDir(portageDir)
+	dirEntries, _ := SystemFS.ReadDir(portageDir)
 	for _, repo := range dirEntries {
 		if !repo.IsDir() {
 			continue
 		}
 
-		packages, _ := os.ReadDir(path.Join(portageDir, repo.Name()))
+		packages, _ := SystemFS.ReadDir(path.Join(portageDir, repo.Name()))
 		total += len(packages)
 	}
 
@@ -132,28 +144,28 @@ func pmFlatpak(args ...any) int {
 	total := 0
 
 	// Count applications
-	apps, err := os.ReadDir(path.Join(flatpakDir, "app"))
+	apps, err := SystemFS.ReadDir(path.Join(flatpakDir, "app"))
 	if err == nil {
 		for _, app := range apps {
 			if strings.HasSuffix(app.Name(), ".Locale") || strings.HasSuffix(app.Name(), ".Debug") {
 				continue
 			}
 
-			dirEntries, _ := os.ReadDir(path.Join(flatpakDir, "app", app.Name(), arch))
+			dirEntries, _ := SystemFS.ReadDir(path.Join(flatpakDir, "app", app.Name(), arch))
 
 			total += len(dirEntries)
 		}
 	}
 
 	// Count runtimes
-	runtimes, err := os.ReadDir(path.Join(flatpakDir, "runtime"))
+	runtimes, err := SystemFS.ReadDir(path.Join(flatpakDir, "runtime"))
 	if err == nil {
 		for _, runtime := range runtimes {
 			if strings.HasSuffix(runtime.Name(), ".Locale") || strings.HasSuffix(runtime.Name(), ".Debug") {
 				continue
 			}
 
-			dirEntries, _ := os.ReadDir(path.Join(flatpakDir, "runtime", runtime.Name(), arch))
+			dirEntries, _ := SystemFS.ReadDir(path.Join(flatpakDir, "runtime", runtime.Name(), arch))
 
 			total += len(dirEntries)
 		}
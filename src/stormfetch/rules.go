@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Recolors a color slot in the module format when a condition on the module's variables matches
+type stormfetchModuleRule struct {
+	When string `yaml:"when"`
+	// Color slot number or color spec
+	Color string `yaml:"color"`
+	// Color slot to recolor, which is the value slot by default
+	Slot int `yaml:"slot"`
+}
+
+type ruleCondition struct {
+	Variable string
+	Operator string
+	Value    string
+}
+
+var ruleConditionRegex = regexp.MustCompile(`^\s*\$?\{?([A-Za-z_][A-Za-z0-9_]*)\}?\s*(>=|<=|==|!=|>|<)\s*(.*?)\s*$`)
+
+// Parses conditions such as "PART_PERCENT > 90"
+func parseRuleCondition(when string) (ruleCondition, error) {
+	match := ruleConditionRegex.FindStringSubmatch(when)
+	if match == nil {
+		return ruleCondition{}, fmt.Errorf("invalid rule condition: %s", when)
+	}
+
+	return ruleCondition{Variable: match[1], Operator: match[2], Value: strings.Trim(match[3], `"'`)}, nil
+}
+
+// Checks whether the condition matches, comparing numerically if both sides are numbers
+func (condition ruleCondition) Matches(variables map[string]string) bool {
+	value, ok := variables[condition.Variable]
+	if !ok {
+		return false
+	}
+
+	comparison := strings.Compare(value, condition.Value)
+	left, errLeft := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	right, errRight := strconv.ParseFloat(strings.TrimSuffix(condition.Value, "%"), 64)
+	if errLeft == nil && errRight == nil {
+		comparison = 0
+		if left < right {
+			comparison = -1
+		} else if left > right {
+			comparison = 1
+		}
+	}
+
+	switch condition.Operator {
+	case ">":
+		return comparison > 0
+	case ">=":
+		return comparison >= 0
+	case "<":
+		return comparison < 0
+	case "<=":
+		return comparison <= 0
+	case "==":
+		return comparison == 0
+	case "!=":
+		return comparison != 0
+	default:
+		return false
+	}
+}
+
+// Returns the color code the rule's slot is replaced with
+func (rule stormfetchModuleRule) colorCode() string {
+	if slot, err := strconv.Atoi(rule.Color); err == nil && slot >= 0 && slot <= 9 {
+		return "%" + strconv.Itoa(slot)
+	}
+	return "%{" + rule.Color + "}"
+}
+
+// Recolors text using the rules matching the variables. The first matching rule for a slot wins
+func applyModuleRules(text string, rules []stormfetchModuleRule, variables map[string]string) string {
+	for _, rule := range rules {
+		condition, err := parseRuleCondition(rule.When)
+		if err != nil || !condition.Matches(variables) {
+			continue
+		}
+
+		slot := rule.Slot
+		if slot == 0 {
+			slot = 4
+		}
+		text = strings.ReplaceAll(text, "%"+strconv.Itoa(slot), rule.colorCode())
+	}
+
+	return text
+}
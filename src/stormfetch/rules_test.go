@@ -0,0 +1,122 @@
+package main
+
+import "testing"
+
+func TestParseRuleCondition(t *testing.T) {
+	tests := []struct {
+		when     string
+		expected ruleCondition
+		valid    bool
+	}{
+		{"PART_PERCENT > 90", ruleCondition{Variable: "PART_PERCENT", Operator: ">", Value: "90"}, true},
+		{"$MEM_PERCENT>=75", ruleCondition{Variable: "MEM_PERCENT", Operator: ">=", Value: "75"}, true},
+		{"${PART_MOUNT} == \"/home\"", ruleCondition{Variable: "PART_MOUNT", Operator: "==", Value: "/home"}, true},
+		{"  NAME != 'Debian'  ", ruleCondition{Variable: "NAME", Operator: "!=", Value: "Debian"}, true},
+		{"LOAD_1 < 0.5", ruleCondition{Variable: "LOAD_1", Operator: "<", Value: "0.5"}, true},
+		{"MEM_PERCENT", ruleCondition{}, false},
+		{"MEM_PERCENT => 10", ruleCondition{}, false},
+		{"1VAR > 10", ruleCondition{}, false},
+	}
+
+	for _, test := range tests {
+		condition, err := parseRuleCondition(test.when)
+		if (err == nil) != test.valid {
+			t.Errorf("parseRuleCondition(%q) error = %v, expected valid = %v", test.when, err, test.valid)
+			continue
+		}
+		if condition != test.expected {
+			t.Errorf("parseRuleCondition(%q) = %+v, expected %+v", test.when, condition, test.expected)
+		}
+	}
+}
+
+func TestRuleConditionMatches(t *testing.T) {
+	variables := map[string]string{
+		"PERCENT": "85",
+		"USAGE":   "85%",
+		"LOAD":    "0.75",
+		"MOUNT":   "/home",
+		"VERSION": "9",
+	}
+
+	tests := []struct {
+		when     string
+		expected bool
+	}{
+		{"PERCENT > 80", true},
+		{"PERCENT > 85", false},
+		{"PERCENT >= 85", true},
+		{"PERCENT < 100", true},
+		{"PERCENT <= 84.9", false},
+		{"PERCENT == 85.0", true},
+		{"USAGE > 80%", true},
+		{"USAGE < 90", true},
+		{"LOAD > 0.5", true},
+		{"MOUNT == /home", true},
+		{"MOUNT != /home", false},
+		{"MOUNT > /boot", true},
+		// Numbers are compared numerically rather than as strings
+		{"VERSION < 10", true},
+		// Unknown variables never match
+		{"UNKNOWN != x", false},
+	}
+
+	for _, test := range tests {
+		condition, err := parseRuleCondition(test.when)
+		if err != nil {
+			t.Fatalf("parseRuleCondition(%q) error = %v", test.when, err)
+		}
+		if matches := condition.Matches(variables); matches != test.expected {
+			t.Errorf("%q matches = %v, expected %v", test.when, matches, test.expected)
+		}
+	}
+}
+
+func TestApplyModuleRules(t *testing.T) {
+	variables := map[string]string{"PERCENT": "95"}
+	format := "%3Disk: %4$PERCENT%"
+
+	tests := []struct {
+		rules    []stormfetchModuleRule
+		expected string
+	}{
+		{nil, format},
+		{[]stormfetchModuleRule{{When: "PERCENT > 99", Color: "red"}}, format},
+		{[]stormfetchModuleRule{{When: "PERCENT > 90", Color: "red"}}, "%3Disk: %{red}$PERCENT%"},
+		{[]stormfetchModuleRule{{When: "PERCENT > 90", Color: "1"}}, "%3Disk: %1$PERCENT%"},
+		{[]stormfetchModuleRule{{When: "PERCENT > 90", Color: "bold", Slot: 3}}, "%{bold}Disk: %4$PERCENT%"},
+		// The first matching rule for a slot wins
+		{[]stormfetchModuleRule{{When: "PERCENT > 90", Color: "red"}, {When: "PERCENT > 70", Color: "yellow"}}, "%3Disk: %{red}$PERCENT%"},
+		{[]stormfetchModuleRule{{When: "PERCENT > 99", Color: "red"}, {When: "PERCENT > 70", Color: "yellow"}}, "%3Disk: %{yellow}$PERCENT%"},
+		// Invalid conditions are ignored
+		{[]stormfetchModuleRule{{When: "PERCENT", Color: "red"}}, format},
+	}
+
+	for _, test := range tests {
+		if text := applyModuleRules(format, test.rules, variables); text != test.expected {
+			t.Errorf("applyModuleRules(%q, %+v) = %q, expected %q", format, test.rules, text, test.expected)
+		}
+	}
+}
+
+func TestApplyModuleRulesToUsageBars(t *testing.T) {
+	bar := usageBar{Width: 4, FilledChar: "#", EmptyChar: "-", Colors: [3]string{"%4", "%3", "%1"}, RestoreColor: "%4", Thresholds: [2]float64{70, 90}}
+	rules := []stormfetchModuleRule{{When: "MEM_PERCENT > 10", Color: "#ff0000"}}
+
+	tests := []struct {
+		percent  float64
+		expected string
+	}{
+		{5, "%3Memory: %4%4%4---- 5%"},
+		{50, "%3Memory: %{#ff0000}%{#ff0000}##%{#ff0000}-- 50%"},
+		{95, "%3Memory: %{#ff0000}%1####%{#ff0000} 95%"},
+	}
+
+	for _, test := range tests {
+		variables := map[string]string{"MEM_BAR": bar.Render(test.percent), "MEM_PERCENT": formatPercent(test.percent)}
+		text := applyModuleRules(expandTemplate("%3Memory: %4$MEM_BAR $MEM_PERCENT%", variables), rules, variables)
+		if text != test.expected {
+			t.Errorf("bar at %v%% = %q, expected %q", test.percent, text, test.expected)
+		}
+	}
+}
@@ -2,18 +2,25 @@ package main
 
 import (
 	"os"
-	"os/exec"
 	"path"
 	"strings"
 	"syscall"
 
-	"github.com/mitchellh/go-ps"
+	"gopkg.in/yaml.v3"
 )
 
 type DistroInfo struct {
-	ID        string
-	LongName  string
-	ShortName string
+	ID              string   `json:"id" yaml:"id"`
+	LongName        string   `json:"long_name" yaml:"long_name"`
+	ShortName       string   `json:"short_name" yaml:"short_name"`
+	IDLike          []string `json:"id_like" yaml:"id_like"`
+	Version         string   `json:"version" yaml:"version"`
+	VersionID       string   `json:"version_id" yaml:"version_id"`
+	VersionCodename string   `json:"version_codename" yaml:"version_codename"`
+	BuildID         string   `json:"build_id" yaml:"build_id"`
+	Variant         string   `json:"variant" yaml:"variant"`
+	HomeURL         string   `json:"home_url" yaml:"home_url"`
+	AnsiColor       string   `json:"ansi_color" yaml:"ansi_color"`
 }
 
 func GetDistroInfo() DistroInfo {
@@ -21,27 +28,51 @@ func GetDistroInfo() DistroInfo {
 		ID:        "unknown",
 		LongName:  "Unknown",
 		ShortName: "Unknown",
+		IDLike:    make([]string, 0),
 	}
 
-	// Detect release file location
-	var releaseFile string
-	if _, err := os.Stat("/bedrock/etc/os-release"); os.Getenv("BEDROCK_RESTRICT") == "" && err == nil {
+	// Detect os-release file location
+	osReleaseFiles := []string{"/etc/os-release", "/usr/lib/os-release"}
+	if os.Getenv("BEDROCK_RESTRICT") == "" {
 		// Using Bedrock Linux
-		releaseFile = "/bedrock/etc/os-release"
-	} else if _, err := os.Stat("/etc/os-release"); err == nil {
-		// Using a regular linux distribution
-		releaseFile = "/etc/os-release"
-	} else {
-		return info
+		osReleaseFiles = append([]string{"/bedrock/etc/os-release"}, osReleaseFiles...)
+	}
+	for _, releaseFile := range osReleaseFiles {
+		if releaseMap, err := ReadKeyValueFile(releaseFile); err == nil {
+			readOsRelease(releaseMap, &info)
+			return info
+		}
 	}
 
-	// Read release file
-	releaseMap, err := ReadKeyValueFile(releaseFile)
-	if err != nil {
-		return info
+	// Fall back to distribution specific release files
+	if releaseMap, err := ReadKeyValueFile("/etc/lsb-release"); err == nil && releaseMap["DISTRIB_ID"] != "" {
+		info.ID = strings.ToLower(strings.ReplaceAll(releaseMap["DISTRIB_ID"], " ", ""))
+		info.ShortName = releaseMap["DISTRIB_ID"]
+		info.LongName = releaseMap["DISTRIB_DESCRIPTION"]
+		if info.LongName == "" {
+			info.LongName = strings.TrimSpace(info.ShortName + " " + releaseMap["DISTRIB_RELEASE"])
+		}
+		info.VersionID = releaseMap["DISTRIB_RELEASE"]
+		info.VersionCodename = releaseMap["DISTRIB_CODENAME"]
+	} else if version, ok := readReleaseFile("/etc/debian_version"); ok {
+		info.ID = "debian"
+		info.ShortName = "Debian GNU/Linux"
+		info.LongName = "Debian GNU/Linux " + version
+		info.VersionID = version
+	} else if release, ok := readReleaseFile("/etc/redhat-release"); ok {
+		readRedHatRelease(release, &info)
+	} else if version, ok := readReleaseFile("/etc/alpine-release"); ok {
+		info.ID = "alpine"
+		info.ShortName = "Alpine Linux"
+		info.LongName = "Alpine Linux v" + version
+		info.VersionID = version
 	}
 
-	// Set struct fields
+	return info
+}
+
+// Sets distro info fields from an os-release file
+func readOsRelease(releaseMap map[string]string, info *DistroInfo) {
 	if id, ok := releaseMap["ID"]; ok {
 		info.ID = id
 	}
@@ -51,13 +82,52 @@ func GetDistroInfo() DistroInfo {
 	if shortName, ok := releaseMap["NAME"]; ok {
 		info.ShortName = shortName
 	}
+	if idLike, ok := releaseMap["ID_LIKE"]; ok {
+		info.IDLike = strings.Fields(idLike)
+	}
+	info.Version = releaseMap["VERSION"]
+	info.VersionID = releaseMap["VERSION_ID"]
+	info.VersionCodename = releaseMap["VERSION_CODENAME"]
+	info.BuildID = releaseMap["BUILD_ID"]
+	info.Variant = releaseMap["VARIANT"]
+	info.HomeURL = releaseMap["HOME_URL"]
+	info.AnsiColor = releaseMap["ANSI_COLOR"]
+}
 
-	return info
+// Returns the first line of a release file
+func readReleaseFile(releaseFile string) (string, bool) {
+	bytes, err := SystemFS.ReadFile(releaseFile)
+	if err != nil {
+		return "", false
+	}
+
+	line, _, _ := strings.Cut(string(bytes), "\n")
+	line = strings.TrimSpace(line)
+	return line, line != ""
+}
+
+// Sets distro info fields from a line such as "CentOS Linux release 7.9.2009 (Core)"
+func readRedHatRelease(release string, info *DistroInfo) {
+	name, version, _ := strings.Cut(release, " release ")
+	version, codename, _ := strings.Cut(version, " ")
+
+	info.ShortName = name
+	info.LongName = release
+	info.VersionID = version
+	info.VersionCodename = strings.Trim(codename, "()")
+	info.IDLike = []string{"rhel", "fedora"}
+
+	switch {
+	case strings.HasPrefix(name, "Red Hat"):
+		info.ID = "rhel"
+	case strings.HasPrefix(name, "Rocky"):
+		info.ID = "rocky"
+	default:
+		info.ID = strings.ToLower(strings.Fields(name + " unknown")[0])
+	}
 }
 
-func GetDistroAsciiArt() string {
-	defaultAscii :=
-		`    .--.
+var defaultAsciiArt = `    .--.
    |o_o |
    |:_/ |
   //   \ \
@@ -65,22 +135,71 @@ func GetDistroAsciiArt() string {
 /'\_   _/'\
 \___)=(___/`
 
-	// Get ascii name to use
-	var asciiName string
-	if Ascii != "" {
-		asciiName = Ascii
-	} else if config.Ascii == "auto" {
-		asciiName = GetDistroInfo().ID
-	} else {
-		asciiName = config.Ascii
+// Returns the names of the ascii art to try in order
+func getAsciiArtNames() []string {
+	if Ascii != "" && !isImageLogoPath(Ascii) {
+		return []string{Ascii}
+	} else if config.Ascii != "auto" && !isImageLogoPath(config.Ascii) && !isImageLogoPath(Ascii) {
+		return []string{config.Ascii}
+	}
+
+	// Use distro ascii art, which is also the fallback for image logos
+	distroInfo := GetDistroInfo()
+	aliases := readAsciiArtAliases()
+	names := make([]string, 0)
+	for _, id := range append([]string{distroInfo.ID}, distroInfo.IDLike...) {
+		id = strings.ToLower(id)
+		names = append(names, id)
+		if alias, ok := aliases[id]; ok {
+			names = append(names, alias)
+		}
+	}
+
+	// Try parts of the distro ID, such as opensuse for opensuse-leap
+	id := strings.ToLower(distroInfo.ID)
+	for i := strings.LastIndexAny(id, "-_"); i > 0; i = strings.LastIndexAny(id, "-_") {
+		id = id[:i]
+		names = append(names, id)
+	}
+
+	return names
+}
+
+// Reads the table of distro IDs mapped to existing ascii art from the system and user config directories
+func readAsciiArtAliases() map[string]string {
+	aliases := make(map[string]string)
+
+	aliasFiles := []string{path.Join(SystemConfigDir, "stormfetch/ascii_aliases.yml")}
+	if userConfDir, err := os.UserConfigDir(); err == nil {
+		aliasFiles = append(aliasFiles, path.Join(userConfDir, "stormfetch/ascii_aliases.yml"))
 	}
 
+	// Aliases in the user config directory take precedence
+	for _, aliasFile := range aliasFiles {
+		bytes, err := os.ReadFile(aliasFile)
+		if err != nil {
+			continue
+		}
+		fileAliases := make(map[string]string)
+		if err := yaml.Unmarshal(bytes, &fileAliases); err != nil {
+			continue
+		}
+		for id, name := range fileAliases {
+			aliases[strings.ToLower(id)] = name
+		}
+	}
+
+	return aliases
+}
+
+// Reads ascii art from the user or system config directory
+func readAsciiArt(asciiName string) (string, bool) {
 	// Check for ascii art in home directory
 	userConfDir, err := os.UserConfigDir()
 	if err == nil {
 		if _, err := os.Stat(path.Join(userConfDir, "stormfetch/ascii/", asciiName)); err == nil {
 			if bytes, err := os.ReadFile(path.Join(userConfDir, "stormfetch/ascii/", asciiName)); err == nil {
-				return strings.TrimRight(string(bytes), "\n")
+				return strings.TrimRight(string(bytes), "\n"), true
 			}
 		}
 	}
@@ -88,11 +207,62 @@ func GetDistroAsciiArt() string {
 	// Check for ascii art in system config directory
 	if _, err := os.Stat(path.Join(SystemConfigDir, "stormfetch/ascii/", asciiName)); err == nil {
 		if bytes, err := os.ReadFile(path.Join(SystemConfigDir, "stormfetch/ascii/", asciiName)); err == nil {
-			return strings.TrimRight(string(bytes), "\n")
+			return strings.TrimRight(string(bytes), "\n"), true
 		}
 	}
 
-	return defaultAscii
+	return "", false
+}
+
+const (
+	LogoSizeAuto   = "auto"
+	LogoSizeSmall  = "small"
+	LogoSizeNormal = "normal"
+	LogoSizeLarge  = "large"
+)
+
+var LogoSizes = []string{LogoSizeAuto, LogoSizeSmall, LogoSizeNormal, LogoSizeLarge}
+
+// Ascii art variants to try for each logo size in order
+var asciiArtVariants = map[string][]string{
+	LogoSizeAuto:   {LogoSizeNormal, LogoSizeSmall, LogoSizeLarge},
+	LogoSizeSmall:  {LogoSizeSmall, LogoSizeNormal, LogoSizeLarge},
+	LogoSizeNormal: {LogoSizeNormal, LogoSizeSmall, LogoSizeLarge},
+	LogoSizeLarge:  {LogoSizeLarge, LogoSizeNormal, LogoSizeSmall},
+}
+
+// Reads the given size variant of ascii art
+func readAsciiArtVariant(asciiName, size string) (string, bool) {
+	switch size {
+	case LogoSizeSmall, LogoSizeLarge:
+		return readAsciiArt(asciiName + "_" + size)
+	default:
+		return readAsciiArt(asciiName)
+	}
+}
+
+// Returns the distro ascii art and the name it was read from, falling back to other size variants and parent
+// distros if the requested one is missing. The name is empty for the default ascii art
+func GetDistroAsciiArt(size string) (string, string) {
+	for _, asciiName := range getAsciiArtNames() {
+		for _, variant := range asciiArtVariants[size] {
+			if asciiArt, ok := readAsciiArtVariant(asciiName, variant); ok {
+				return asciiArt, asciiName
+			}
+		}
+	}
+
+	return defaultAsciiArt, ""
+}
+
+// Splits the color header from ascii art
+func splitAsciiArtHeader(asciiArt string) (string, string) {
+	if !strings.HasPrefix(asciiArt, "#/") {
+		return "", asciiArt
+	}
+
+	header, body, _ := strings.Cut(asciiArt, "\n")
+	return header, body
 }
 
 func GetArch() string {
@@ -133,19 +303,15 @@ func GetKernel() (string, string) {
 }
 
 func GetInitSystem() string {
-	process, err := ps.FindProcess(1)
-	if err != nil {
-		return ""
-	}
-
 	// Return if init system can't be found
-	if process == nil {
+	initName := getInitExecutable()
+	if initName == "" {
 		return ""
 	}
 
 	// Special cases
 	// OpenRC check
-	if _, err := os.Stat("/usr/sbin/openrc"); err == nil {
+	if _, err := SystemFS.Stat("/usr/sbin/openrc"); err == nil {
 		openrcVersion := runCommand("openrc --version | awk '{print $3}'", "/bin/sh")
 		if openrcVersion != "" {
 			return "OpenRC " + openrcVersion
@@ -155,7 +321,6 @@ func GetInitSystem() string {
 	}
 
 	// Default PID 1 process name checking
-	initName := process.Executable()
 	initVersion := ""
 	switch initName {
 	case "systemd":
@@ -179,23 +344,25 @@ func GetInitSystem() string {
 }
 
 func GetLibc() string {
-	checkLibcOutput, err := exec.Command("ldd", "/usr/bin/ls").Output()
-	if err != nil {
+	checkLibcOutput := runCommand("ldd /usr/bin/ls", "/bin/sh")
+	if checkLibcOutput == "" {
 		return "Unknown"
 	}
 
-	if strings.Contains(string(checkLibcOutput), "ld-musl") {
+	if strings.Contains(checkLibcOutput, "ld-musl") {
 		// Using Musl Libc
-		output, _ := exec.Command("ldd").CombinedOutput()
-		return "Musl " + strings.TrimPrefix(strings.Split(strings.TrimSpace(string(output)), "\n")[1], "Version ")
+		output := strings.Split(runCommand("ldd 2>&1 || true", "/bin/sh"), "\n")
+		if len(output) < 2 {
+			return "Musl"
+		}
+		return "Musl " + strings.TrimPrefix(output[1], "Version ")
 	} else {
 		// Using Glibc
-		cmd := exec.Command("ldd", "--version")
-		output, err := cmd.Output()
-		if err != nil {
+		output := runCommand("ldd --version", "/bin/sh")
+		if output == "" {
 			return "Glibc"
 		}
-		outputSplit := strings.Split(strings.Split(strings.TrimSpace(string(output)), "\n")[0], " ")
+		outputSplit := strings.Split(strings.Split(output, "\n")[0], " ")
 		ver := outputSplit[len(outputSplit)-1]
 		return "Glibc " + ver
 	}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetDistroInfo(t *testing.T) {
+	tests := []struct {
+		root     string
+		expected DistroInfo
+	}{
+		{"debian", DistroInfo{
+			ID:              "debian",
+			LongName:        "Debian GNU/Linux 12 (bookworm)",
+			ShortName:       "Debian GNU/Linux",
+			IDLike:          []string{},
+			Version:         "12 (bookworm)",
+			VersionID:       "12",
+			VersionCodename: "bookworm",
+			HomeURL:         "https://www.debian.org/",
+		}},
+		{"fedora", DistroInfo{
+			ID:        "fedora",
+			LongName:  "Fedora Linux 40 (Container Image)",
+			ShortName: "Fedora Linux",
+			IDLike:    []string{},
+			Version:   "40 (Container Image)",
+			VersionID: "40",
+			Variant:   "Container Image",
+			AnsiColor: "0;38;2;60;110;180",
+		}},
+		{"alpine", DistroInfo{
+			ID:        "alpine",
+			LongName:  "Alpine Linux v3.19.1",
+			ShortName: "Alpine Linux",
+			IDLike:    []string{},
+			VersionID: "3.19.1",
+		}},
+		{"centos", DistroInfo{
+			ID:              "centos",
+			LongName:        "CentOS Linux release 7.9.2009 (Core)",
+			ShortName:       "CentOS Linux",
+			IDLike:          []string{"rhel", "fedora"},
+			VersionID:       "7.9.2009",
+			VersionCodename: "Core",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.root, func(t *testing.T) {
+			useFixtureRoot(t, test.root)
+			if info := GetDistroInfo(); !reflect.DeepEqual(info, test.expected) {
+				t.Errorf("GetDistroInfo() = %+v, expected %+v", info, test.expected)
+			}
+		})
+	}
+}
+
+func TestHostCommandsIgnoredForOtherRoots(t *testing.T) {
+	useFixtureRoot(t, "alpine")
+
+	if output := runCommand("echo host", "/bin/sh"); output != "" {
+		t.Errorf("runCommand() = %q, expected no output", output)
+	}
+	if executables, err := getProcessExecutables(); err != nil || executables != nil {
+		t.Errorf("getProcessExecutables() = %v, %v, expected none", executables, err)
+	}
+	if initName := getInitExecutable(); initName != "" {
+		t.Errorf("getInitExecutable() = %q, expected none", initName)
+	}
+}
+
+func TestCommandKilledAfterDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if output := runShellCommand(ctx, "sleep 5; echo done", "/bin/sh"); output != "" {
+		t.Errorf("runShellCommand() = %q, expected no output", output)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("runShellCommand() took %v, expected the command to be killed", elapsed)
+	}
+}
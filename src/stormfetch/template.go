@@ -0,0 +1,166 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Expands a module format template using the given variables. Supported syntax:
+//
+//	$VAR, ${VAR}                variable value
+//	${VAR:-default}             default value if the variable is empty
+//	${VAR:upper}, ${VAR:lower}  case changes
+//	${VAR:pad=N}                pad to N cells, aligning right if N is negative
+//	${VAR:trunc=N}              truncate to N cells with an ellipsis
+//	${VAR:upper:pad=N}          filters can be chained
+//	{{if VAR}}...{{end}}        text shown if the variable is not empty, with optional {{else}} and negation using !VAR
+func expandTemplate(format string, variables map[string]string) string {
+	return expandVariables(expandConditionals(format, variables), variables)
+}
+
+// Evaluates {{if}} blocks, including nested ones
+func expandConditionals(format string, variables map[string]string) string {
+	builder := strings.Builder{}
+	for {
+		start := strings.Index(format, "{{if ")
+		if start < 0 {
+			builder.WriteString(format)
+			return builder.String()
+		}
+		builder.WriteString(format[:start])
+
+		// Find condition
+		conditionEnd := strings.Index(format[start:], "}}")
+		if conditionEnd < 0 {
+			builder.WriteString(format[start:])
+			return builder.String()
+		}
+		condition := strings.TrimSpace(format[start+len("{{if ") : start+conditionEnd])
+		body := format[start+conditionEnd+len("}}"):]
+
+		// Find the matching else and end tags
+		depth := 0
+		elseIndex, endIndex := -1, -1
+		for i := 0; i < len(body) && endIndex < 0; i++ {
+			switch {
+			case strings.HasPrefix(body[i:], "{{if "):
+				depth++
+			case strings.HasPrefix(body[i:], "{{else}}") && depth == 0 && elseIndex < 0:
+				elseIndex = i
+			case strings.HasPrefix(body[i:], "{{end}}") && depth == 0:
+				endIndex = i
+			case strings.HasPrefix(body[i:], "{{end}}"):
+				depth--
+			}
+		}
+		if endIndex < 0 {
+			// Unterminated block, keep the rest as is
+			builder.WriteString(format[start:])
+			return builder.String()
+		}
+
+		thenText, elseText := body[:endIndex], ""
+		if elseIndex >= 0 {
+			thenText, elseText = body[:elseIndex], body[elseIndex+len("{{else}}"):endIndex]
+		}
+
+		// Check if the variable is set, or unset when negated
+		name, negated := strings.CutPrefix(condition, "!")
+		name = strings.Trim(name, "${}")
+		if (variables[name] != "") != negated {
+			builder.WriteString(expandConditionals(thenText, variables))
+		} else {
+			builder.WriteString(expandConditionals(elseText, variables))
+		}
+
+		format = body[endIndex+len("{{end}}"):]
+	}
+}
+
+// Expands $VAR and ${VAR:filters} references
+func expandVariables(format string, variables map[string]string) string {
+	builder := strings.Builder{}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '$' || i+1 >= len(format) {
+			builder.WriteByte(format[i])
+			continue
+		}
+
+		// Braced variable with filters
+		if format[i+1] == '{' {
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				builder.WriteString(format[i:])
+				break
+			}
+			builder.WriteString(expandVariableExpression(format[i+2:i+end], variables))
+			i += end
+			continue
+		}
+
+		// Plain variable
+		end := i + 1
+		for end < len(format) && isTemplateNameChar(format[end]) {
+			end++
+		}
+		if end == i+1 {
+			builder.WriteByte('$')
+			continue
+		}
+		builder.WriteString(variables[format[i+1:end]])
+		i = end - 1
+	}
+
+	return builder.String()
+}
+
+// Variable names only contain ASCII letters, digits and underscores like in os.Expand
+func isTemplateNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// Evaluates expressions such as "VAR:-default" or "VAR:upper:pad=10"
+func expandVariableExpression(expression string, variables map[string]string) string {
+	name, filters, _ := strings.Cut(expression, ":")
+	value := variables[name]
+
+	for filters != "" {
+		// Default values take up the rest of the expression
+		if defaultValue, ok := strings.CutPrefix(filters, "-"); ok {
+			if value == "" {
+				value = defaultValue
+			}
+			break
+		}
+
+		var filter string
+		filter, filters, _ = strings.Cut(filters, ":")
+		filterName, argument, _ := strings.Cut(filter, "=")
+		number, _ := strconv.Atoi(argument)
+
+		switch filterName {
+		case "upper":
+			value = strings.ToUpper(value)
+		case "lower":
+			value = strings.ToLower(value)
+		case "pad":
+			padding := strings.Repeat(" ", max(abs(number)-displayWidth(value), 0))
+			if number < 0 {
+				value = padding + value
+			} else {
+				value += padding
+			}
+		case "trunc":
+			value = truncateDisplayWidth(value, number)
+		}
+	}
+
+	return value
+}
+
+func abs(number int) int {
+	if number < 0 {
+		return -number
+	}
+	return number
+}
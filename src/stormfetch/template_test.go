@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestExpandTemplate(t *testing.T) {
+	variables := map[string]string{
+		"NAME":    "Debian",
+		"VERSION": "12",
+		"EMPTY":   "",
+		"LONG":    "Debian GNU/Linux",
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"$NAME", "Debian"},
+		{"${NAME}", "Debian"},
+		{"$NAME $VERSION", "Debian 12"},
+		{"$UNKNOWN", ""},
+		{"costs $ 5", "costs $ 5"},
+		{"${EMPTY:-none}", "none"},
+		{"${NAME:-none}", "Debian"},
+		{"${EMPTY:-a:b}", "a:b"},
+		{"${NAME:upper}", "DEBIAN"},
+		{"${NAME:lower}", "debian"},
+		{"[${NAME:pad=8}]", "[Debian  ]"},
+		{"[${NAME:pad=-8}]", "[  Debian]"},
+		{"${LONG:trunc=7}", "Debian…"},
+		{"${NAME:upper:pad=8}|", "DEBIAN  |"},
+		{"{{if VERSION}}v$VERSION{{end}}", "v12"},
+		{"{{if EMPTY}}set{{else}}unset{{end}}", "unset"},
+		{"{{if !EMPTY}}unset{{end}}", "unset"},
+		{"{{if NAME}}a{{if EMPTY}}b{{else}}c{{end}}d{{end}}e", "acde"},
+		{"{{if EMPTY}}a{{if NAME}}b{{end}}c{{else}}d{{end}}", "d"},
+		{"{{if NAME}}unterminated", "{{if NAME}}unterminated"},
+		{"$NAME│ $NAMEé end", "Debian│ Debiané end"},
+		{"${NAME}│", "Debian│"},
+		{"%3Name: %4$NAME", "%3Name: %4Debian"},
+	}
+
+	for _, test := range tests {
+		if result := expandTemplate(test.format, variables); result != test.expected {
+			t.Errorf("expandTemplate(%q) = %q, expected %q", test.format, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+type terminalSize struct {
+	Rows    uint16
+	Columns uint16
+	XPixels uint16
+	YPixels uint16
+}
+
+// Queries the terminal size of stdout. Returns nil if stdout is not a terminal
+func getTerminalSize() *terminalSize {
+	size := terminalSize{}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdout.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+	if errno != 0 || size.Columns == 0 {
+		return nil
+	}
+
+	return &size
+}
+
+// Checks whether the file descriptor refers to a terminal
+func isTerminal(fd uintptr) bool {
+	termios := syscall.Termios{}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(syscall.TCGETS), uintptr(unsafe.Pointer(&termios)))
+	return errno == 0
+}
+
+// Returns the width and height of a terminal cell in pixels
+func getTerminalCellSize() (int, int) {
+	size := getTerminalSize()
+	if size == nil || size.XPixels == 0 || size.YPixels == 0 || size.Rows == 0 {
+		// Assume the common 1:2 cell ratio
+		return 8, 16
+	}
+
+	return int(size.XPixels) / int(size.Columns), int(size.YPixels) / int(size.Rows)
+}
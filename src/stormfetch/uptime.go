@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Uptime struct {
+	Duration time.Duration
+	BootTime time.Time
+}
+
+func GetUptime() *Uptime {
+	// Read seconds since boot
+	bytes, err := SystemFS.ReadFile("/proc/uptime")
+	if err != nil {
+		return nil
+	}
+	fields := strings.Fields(string(bytes))
+	if len(fields) == 0 {
+		return nil
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil
+	}
+	uptime := Uptime{Duration: time.Duration(seconds) * time.Second}
+
+	// Read boot time, falling back to calculating it from the uptime
+	uptime.BootTime = time.Now().Add(-uptime.Duration)
+	if bytes, err := SystemFS.ReadFile("/proc/stat"); err == nil {
+		for line := range strings.SplitSeq(string(bytes), "\n") {
+			if btime, ok := strings.CutPrefix(line, "btime "); ok {
+				if timestamp, err := strconv.ParseInt(strings.TrimSpace(btime), 10, 64); err == nil {
+					uptime.BootTime = time.Unix(timestamp, 0)
+				}
+				break
+			}
+		}
+	}
+
+	return &uptime
+}
+
+func (uptime Uptime) Days() int {
+	return int(uptime.Duration.Hours()) / 24
+}
+
+func (uptime Uptime) Hours() int {
+	return int(uptime.Duration.Hours()) % 24
+}
+
+func (uptime Uptime) Minutes() int {
+	return int(uptime.Duration.Minutes()) % 60
+}
+
+// Returns the uptime in a human readable form such as "2 days, 3 hours, 15 minutes"
+func (uptime Uptime) String() string {
+	parts := make([]string, 0)
+	for _, part := range []struct {
+		Value int
+		Unit  string
+	}{{uptime.Days(), "day"}, {uptime.Hours(), "hour"}, {uptime.Minutes(), "minute"}} {
+		if part.Value == 1 {
+			parts = append(parts, fmt.Sprintf("%d %s", part.Value, part.Unit))
+		} else if part.Value > 1 {
+			parts = append(parts, fmt.Sprintf("%d %ss", part.Value, part.Unit))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "less than a minute"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetUptime(t *testing.T) {
+	useFixtureRoot(t, "debian")
+
+	uptime := GetUptime()
+	if uptime == nil {
+		t.Fatal("GetUptime() = nil")
+	}
+	if expected := 93784 * time.Second; uptime.Duration != expected {
+		t.Errorf("GetUptime().Duration = %s, expected %s", uptime.Duration, expected)
+	}
+	if expected := time.Unix(1700000000, 0); !uptime.BootTime.Equal(expected) {
+		t.Errorf("GetUptime().BootTime = %s, expected %s", uptime.BootTime, expected)
+	}
+	if expected := "1 day, 2 hours, 3 minutes"; uptime.String() != expected {
+		t.Errorf("GetUptime().String() = %q, expected %q", uptime.String(), expected)
+	}
+
+	useFixtureRoot(t, "alpine")
+	if uptime := GetUptime(); uptime != nil {
+		t.Errorf("GetUptime() = %+v, expected nil", uptime)
+	}
+}
+
+func TestUptimeString(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{30 * time.Second, "less than a minute"},
+		{time.Minute, "1 minute"},
+		{2*time.Hour + time.Minute, "2 hours, 1 minute"},
+		{3 * 24 * time.Hour, "3 days"},
+	}
+
+	for _, test := range tests {
+		if uptime := (Uptime{Duration: test.duration}); uptime.String() != test.expected {
+			t.Errorf("Uptime{%s}.String() = %q, expected %q", test.duration, uptime.String(), test.expected)
+		}
+	}
+}
@@ -2,22 +2,19 @@ package main
 
 import (
 	"log"
-	"os"
 	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
-
-	"github.com/mitchellh/go-ps"
 )
 
 type DEWM struct {
-	Name string
-	Type string
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
 }
 
 func GetShell() string {
-	file, err := os.ReadFile("/etc/passwd")
+	file, err := SystemFS.ReadFile("/etc/passwd")
 	if err != nil {
 		return ""
 	}
@@ -29,7 +26,7 @@ func GetShell() string {
 			continue
 		}
 		userInfo := strings.Split(line, ":")
-		if userInfo[2] == strconv.Itoa(os.Getuid()) {
+		if userInfo[2] == strconv.Itoa(getUid()) {
 			shell = userInfo[6]
 		}
 	}
@@ -60,14 +57,10 @@ func GetShell() string {
 }
 
 func GetDEWM() DEWM {
-	processes, err := ps.Processes()
+	executables, err := getProcessExecutables()
 	if err != nil {
 		log.Fatalf("Error: could not get processes: %s", err)
 	}
-	var executables []string
-	for _, process := range processes {
-		executables = append(executables, process.Executable())
-	}
 
 	processExists := func(process string) bool {
 		return slices.Contains(executables, process)
@@ -214,7 +207,7 @@ func GetDEWM() DEWM {
 }
 
 func GetDisplayProtocol() string {
-	protocol := os.Getenv("XDG_SESSION_TYPE")
+	protocol := getSessionType()
 	if protocol == "x11" {
 		return "X11"
 	} else if protocol == "wayland" {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"os"
@@ -8,6 +9,9 @@ import (
 	"path"
 	"regexp"
 	"strings"
+	"syscall"
+
+	"github.com/mitchellh/go-ps"
 )
 
 func FormatBytes(bytes uint64) string {
@@ -29,18 +33,18 @@ func FormatBytes(bytes uint64) string {
 	return fmt.Sprintf("%.1fYiB", bf)
 }
 
+var ansiiRegex = regexp.MustCompile("[\u001B\u009B][[\\]()#;?]*(?:(?:(?:[a-zA-Z\\d]*(?:;[a-zA-Z\\d]*)*)?\u0007)|(?:(?:\\d{1,4}(?:;\\d{0,4})*)?[\\dA-PRZcf-ntqry=><~]))")
+
 func StripAnsii(str string) string {
-	const ansi = "[\u001B\u009B][[\\]()#;?]*(?:(?:(?:[a-zA-Z\\d]*(?:;[a-zA-Z\\d]*)*)?\u0007)|(?:(?:\\d{1,4}(?:;\\d{0,4})*)?[\\dA-PRZcf-ntqry=><~]))"
-	var re = regexp.MustCompile(ansi)
-	return re.ReplaceAllString(str, "")
+	return ansiiRegex.ReplaceAllString(str, "")
 }
 
 func ReadKeyValueFile(filepath string) (map[string]string, error) {
 	ret := make(map[string]string)
-	if _, err := os.Stat(filepath); err != nil {
+	if _, err := SystemFS.Stat(filepath); err != nil {
 		return nil, err
 	}
-	bytes, err := os.ReadFile(filepath)
+	bytes, err := SystemFS.ReadFile(filepath)
 	if err != nil {
 		return nil, err
 	}
@@ -100,7 +104,35 @@ func fetchAmdGpuName(productId, revision string) (string, error) {
 }
 
 func runCommand(command string, shell string) string {
-	cmd := exec.Command(shell, "-c", command)
+	return runCommandContext(context.Background(), command, shell)
+}
+
+// Runs a command that is killed once the context is done
+func runCommandContext(ctx context.Context, command string, shell string) string {
+	// Use command output from replayed system capture
+	if replayedCommands != nil {
+		return replayedCommands[command]
+	}
+
+	// Host commands don't describe other roots
+	if !isHostRoot() {
+		return ""
+	}
+
+	output := runShellCommand(ctx, command, shell)
+	recordCommandOutput(command, output)
+
+	return output
+}
+
+func runShellCommand(ctx context.Context, command string, shell string) string {
+	cmd := exec.CommandContext(ctx, shell, "-c", command)
+
+	// Kill the whole process group so commands started by the shell don't outlive it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
 	workdir, err := os.Getwd()
 	if err != nil {
 		return ""
@@ -113,3 +145,59 @@ func runCommand(command string, shell string) string {
 	}
 	return strings.TrimSpace(string(out))
 }
+
+// Returns the executable names of running processes, which are unknown for roots other than the host
+func getProcessExecutables() ([]string, error) {
+	if replayedSession != nil {
+		return replayedSession.Executables, nil
+	}
+	if !isHostRoot() {
+		return nil, nil
+	}
+
+	processes, err := ps.Processes()
+	if err != nil {
+		return nil, err
+	}
+	executables := make([]string, 0, len(processes))
+	for _, process := range processes {
+		executables = append(executables, process.Executable())
+	}
+
+	return executables, nil
+}
+
+// Returns the executable name of PID 1, or an empty string if it can't be found
+func getInitExecutable() string {
+	if replayedSession != nil {
+		return replayedSession.InitExecutable
+	}
+	if !isHostRoot() {
+		return ""
+	}
+
+	process, err := ps.FindProcess(1)
+	if err != nil || process == nil {
+		return ""
+	}
+
+	return process.Executable()
+}
+
+// Returns the user ID matched against /etc/passwd entries
+func getUid() int {
+	if replayedSession != nil {
+		return replayedSession.Uid
+	}
+
+	return os.Getuid()
+}
+
+// Returns the graphical session type, such as x11, wayland or tty
+func getSessionType() string {
+	if replayedSession != nil {
+		return replayedSession.SessionType
+	}
+
+	return os.Getenv("XDG_SESSION_TYPE")
+}
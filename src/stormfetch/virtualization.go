@@ -0,0 +1,184 @@
+package main
+
+import (
+	"strings"
+)
+
+const (
+	VirtualizationTypeContainer = "container"
+	VirtualizationTypeVM        = "vm"
+)
+
+type Virtualization struct {
+	Type string `json:"type" yaml:"type"`
+	Name string `json:"name" yaml:"name"`
+}
+
+// Container names reported in the container variable of PID 1's environment
+var containerEnvNames = map[string]string{
+	"docker":         "Docker",
+	"podman":         "Podman",
+	"lxc":            "LXC",
+	"lxc-libvirt":    "LXC",
+	"systemd-nspawn": "systemd-nspawn",
+	"oci":            "OCI",
+	"wsl":            "WSL",
+}
+
+// Container names matched against the cgroup paths of PID 1
+var containerCgroupNames = [][2]string{
+	{"libpod", "Podman"},
+	{"docker", "Docker"},
+	{"kubepods", "Kubernetes"},
+	{"lxc", "LXC"},
+}
+
+// Hypervisor names matched against the DMI system vendor and product name
+var hypervisorDMINames = [][2]string{
+	{"KVM", "KVM"},
+	{"QEMU", "QEMU"},
+	{"VMware", "VMware"},
+	{"VirtualBox", "VirtualBox"},
+	{"innotek", "VirtualBox"},
+	{"Microsoft Corporation Virtual Machine", "Hyper-V"},
+	{"Xen", "Xen"},
+	{"Parallels", "Parallels"},
+	{"Bochs", "Bochs"},
+	{"Amazon EC2", "Amazon EC2"},
+	{"Google Compute Engine", "Google Compute Engine"},
+}
+
+// Hypervisor names matched against the clocksources provided by paravirtualized guests
+var hypervisorClocksourceNames = [][2]string{
+	{"kvm-clock", "KVM"},
+	{"hyperv_clocksource", "Hyper-V"},
+	{"xen", "Xen"},
+}
+
+// Returns the container or hypervisor stormfetch is running in, or nil on bare metal
+func GetVirtualization() *Virtualization {
+	if container := getContainerName(); container != "" {
+		return &Virtualization{Type: VirtualizationTypeContainer, Name: container}
+	}
+	if hypervisor := getHypervisorName(); hypervisor != "" {
+		return &Virtualization{Type: VirtualizationTypeVM, Name: hypervisor}
+	}
+
+	return nil
+}
+
+func getContainerName() string {
+	// Marker files created by container engines
+	if _, err := SystemFS.Stat("/.dockerenv"); err == nil {
+		return "Docker"
+	}
+	if _, err := SystemFS.Stat("/run/.containerenv"); err == nil {
+		return "Podman"
+	}
+
+	// Container variable set by container managers
+	if bytes, err := SystemFS.ReadFile("/proc/1/environ"); err == nil {
+		for variable := range strings.SplitSeq(string(bytes), "\x00") {
+			if value, ok := strings.CutPrefix(variable, "container="); ok && value != "" {
+				if name, ok := containerEnvNames[value]; ok {
+					return name
+				}
+				return value
+			}
+		}
+	}
+
+	// Cgroups of PID 1
+	if bytes, err := SystemFS.ReadFile("/proc/1/cgroup"); err == nil {
+		for line := range strings.SplitSeq(string(bytes), "\n") {
+			for _, cgroupName := range containerCgroupNames {
+				if strings.Contains(line, cgroupName[0]) {
+					return cgroupName[1]
+				}
+			}
+		}
+	}
+
+	// Windows Subsystem for Linux
+	if bytes, err := SystemFS.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		osRelease := strings.ToLower(string(bytes))
+		if strings.Contains(osRelease, "wsl2") {
+			return "WSL2"
+		} else if strings.Contains(osRelease, "microsoft") {
+			return "WSL"
+		}
+	}
+
+	return ""
+}
+
+func getHypervisorName() string {
+	// KVM and Xen guests usually report the QEMU machine type in DMI system information, so check the
+	// paravirtualization interfaces first
+	dmiName := getDMIHypervisorName()
+	if dmiName == "" || dmiName == "QEMU" || dmiName == "Bochs" {
+		if name := getParavirtHypervisorName(); name != "" {
+			return name
+		}
+	}
+	if dmiName != "" {
+		return dmiName
+	}
+
+	// Check for the hypervisor CPU flag
+	bytes, err := SystemFS.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	for line := range strings.SplitSeq(string(bytes), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "flags" && strings.Contains(value, " hypervisor") {
+			return "Unknown hypervisor"
+		}
+	}
+
+	return ""
+}
+
+// Matches DMI system information against known hypervisors
+func getDMIHypervisorName() string {
+	dmiInfo := make([]string, 0)
+	for _, file := range []string{"sys_vendor", "product_name"} {
+		if bytes, err := SystemFS.ReadFile("/sys/devices/virtual/dmi/id/" + file); err == nil {
+			dmiInfo = append(dmiInfo, strings.TrimSpace(string(bytes)))
+		}
+	}
+	dmiString := strings.Join(dmiInfo, " ")
+	for _, hypervisorName := range hypervisorDMINames {
+		if strings.Contains(dmiString, hypervisorName[0]) {
+			return hypervisorName[1]
+		}
+	}
+
+	return ""
+}
+
+// Returns the hypervisor reported by the kernel's hypervisor interface or paravirtualized clocksources
+func getParavirtHypervisorName() string {
+	if bytes, err := SystemFS.ReadFile("/sys/hypervisor/type"); err == nil {
+		if hypervisorType := strings.TrimSpace(string(bytes)); hypervisorType == "xen" {
+			return "Xen"
+		} else if hypervisorType != "" {
+			return hypervisorType
+		}
+	}
+
+	bytes, err := SystemFS.ReadFile("/sys/devices/system/clocksource/clocksource0/available_clocksource")
+	if err != nil {
+		return ""
+	}
+	for _, clocksource := range strings.Fields(string(bytes)) {
+		for _, hypervisorName := range hypervisorClocksourceNames {
+			if strings.HasPrefix(clocksource, hypervisorName[0]) {
+				return hypervisorName[1]
+			}
+		}
+	}
+
+	return ""
+}
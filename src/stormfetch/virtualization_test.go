@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetVirtualization(t *testing.T) {
+	tests := []struct {
+		root     string
+		expected *Virtualization
+	}{
+		{"debian", nil},
+		{"alpine", &Virtualization{Type: VirtualizationTypeContainer, Name: "Docker"}},
+		{"fedora", &Virtualization{Type: VirtualizationTypeContainer, Name: "Podman"}},
+		{"centos", &Virtualization{Type: VirtualizationTypeVM, Name: "VMware"}},
+		{"kvm", &Virtualization{Type: VirtualizationTypeVM, Name: "KVM"}},
+		{"qemu", &Virtualization{Type: VirtualizationTypeVM, Name: "QEMU"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.root, func(t *testing.T) {
+			useFixtureRoot(t, test.root)
+			if virtualization := GetVirtualization(); !reflect.DeepEqual(virtualization, test.expected) {
+				t.Errorf("GetVirtualization() = %+v, expected %+v", virtualization, test.expected)
+			}
+		})
+	}
+}
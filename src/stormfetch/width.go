@@ -0,0 +1,148 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ranges of characters that take up two terminal cells
+var wideCharacterRanges = [][2]rune{
+	{0x1100, 0x115F},   // Hangul Jamo
+	{0x231A, 0x231B},   // Watch, hourglass
+	{0x2329, 0x232A},   // Angle brackets
+	{0x23E9, 0x23EC},   // Media control symbols
+	{0x23F0, 0x23F0},   // Alarm clock
+	{0x23F3, 0x23F3},   // Hourglass with flowing sand
+	{0x25FD, 0x25FE},   // Medium small squares
+	{0x2614, 0x2615},   // Umbrella, hot beverage
+	{0x2648, 0x2653},   // Zodiac signs
+	{0x267F, 0x267F},   // Wheelchair symbol
+	{0x2693, 0x2693},   // Anchor
+	{0x26A1, 0x26A1},   // High voltage
+	{0x26AA, 0x26AB},   // Medium circles
+	{0x26BD, 0x26BE},   // Soccer ball, baseball
+	{0x26C4, 0x26C5},   // Snowman, sun behind cloud
+	{0x26CE, 0x26CE},   // Ophiuchus
+	{0x26D4, 0x26D4},   // No entry
+	{0x26EA, 0x26EA},   // Church
+	{0x26F2, 0x26F3},   // Fountain, flag in hole
+	{0x26F5, 0x26F5},   // Sailboat
+	{0x26FA, 0x26FA},   // Tent
+	{0x26FD, 0x26FD},   // Fuel pump
+	{0x2705, 0x2705},   // Check mark button
+	{0x270A, 0x270B},   // Raised fists
+	{0x2728, 0x2728},   // Sparkles
+	{0x274C, 0x274C},   // Cross mark
+	{0x274E, 0x274E},   // Cross mark button
+	{0x2753, 0x2755},   // Question and exclamation marks
+	{0x2757, 0x2757},   // Exclamation mark
+	{0x2795, 0x2797},   // Plus, minus, division
+	{0x27B0, 0x27B0},   // Curly loop
+	{0x27BF, 0x27BF},   // Double curly loop
+	{0x2B1B, 0x2B1C},   // Large squares
+	{0x2B50, 0x2B50},   // Star
+	{0x2B55, 0x2B55},   // Hollow red circle
+	{0x2E80, 0x303E},   // CJK radicals, punctuation
+	{0x3041, 0x33FF},   // Hiragana, Katakana, CJK compatibility
+	{0x3400, 0x4DBF},   // CJK unified ideographs extension A
+	{0x4E00, 0x9FFF},   // CJK unified ideographs
+	{0xA000, 0xA4CF},   // Yi
+	{0xA960, 0xA97F},   // Hangul Jamo extended A
+	{0xAC00, 0xD7A3},   // Hangul syllables
+	{0xF900, 0xFAFF},   // CJK compatibility ideographs
+	{0xFE10, 0xFE19},   // Vertical forms
+	{0xFE30, 0xFE6F},   // CJK compatibility forms, small form variants
+	{0xFF00, 0xFF60},   // Fullwidth forms
+	{0xFFE0, 0xFFE6},   // Fullwidth signs
+	{0x16FE0, 0x16FE4}, // Ideographic symbols
+	{0x17000, 0x18AFF}, // Tangut
+	{0x1B000, 0x1B2FF}, // Kana supplement and extensions
+	{0x1F004, 0x1F004}, // Mahjong tile
+	{0x1F0CF, 0x1F0CF}, // Playing card
+	{0x1F18E, 0x1F18E}, // AB button
+	{0x1F191, 0x1F19A}, // Squared words
+	{0x1F200, 0x1F251}, // Enclosed ideographic supplement
+	{0x1F300, 0x1F64F}, // Miscellaneous symbols and pictographs, emoticons
+	{0x1F680, 0x1F6FF}, // Transport and map symbols
+	{0x1F7E0, 0x1F7EB}, // Colored circles and squares
+	{0x1F90C, 0x1F9FF}, // Supplemental symbols and pictographs
+	{0x1FA70, 0x1FAFF}, // Symbols and pictographs extended A
+	{0x20000, 0x2FFFD}, // CJK unified ideographs extensions B-F
+	{0x30000, 0x3FFFD}, // CJK unified ideographs extension G
+}
+
+// Returns the amount of terminal cells a character takes up
+func runeWidth(r rune) int {
+	// Control, combining and format characters (including zero-width joiners) take up no space
+	if r == 0 || unicode.IsControl(r) || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	// Variation selectors
+	if (r >= 0xFE00 && r <= 0xFE0F) || (r >= 0xE0100 && r <= 0xE01EF) {
+		return 0
+	}
+
+	if r < wideCharacterRanges[0][0] {
+		return 1
+	}
+	for _, wideRange := range wideCharacterRanges {
+		if r >= wideRange[0] && r <= wideRange[1] {
+			return 2
+		}
+	}
+
+	return 1
+}
+
+// Returns the amount of terminal cells a string without escape sequences takes up
+func displayWidth(str string) int {
+	width := 0
+	joined := false
+	for _, r := range str {
+		// Characters joined to the previous one using a zero-width joiner are rendered as a single glyph
+		if joined {
+			joined = false
+			continue
+		}
+		if r == 0x200D {
+			joined = true
+			continue
+		}
+
+		width += runeWidth(r)
+	}
+
+	return width
+}
+
+// Truncates a string containing escape sequences to the given width in terminal cells, ending it with an ellipsis
+func truncateDisplayWidth(str string, width int) string {
+	if displayWidth(StripAnsii(str)) <= width {
+		return str
+	}
+	if width <= 0 {
+		return ""
+	}
+
+	builder := strings.Builder{}
+	currentWidth := 0
+	for i := 0; i < len(str); {
+		// Keep escape sequences
+		if loc := ansiiRegex.FindStringIndex(str[i:]); loc != nil && loc[0] == 0 {
+			builder.WriteString(str[i : i+loc[1]])
+			i += loc[1]
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(str[i:])
+		if currentWidth+runeWidth(r) > width-1 {
+			break
+		}
+		builder.WriteRune(r)
+		currentWidth += runeWidth(r)
+		i += size
+	}
+
+	return builder.String() + "…"
+}
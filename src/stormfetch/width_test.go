@@ -0,0 +1,88 @@
+package main
+
+import "testing"
+
+func TestRuneWidth(t *testing.T) {
+	tests := []struct {
+		r        rune
+		expected int
+	}{
+		{'a', 1},
+		{'█', 1},
+		{'é', 1},
+		{'\t', 0},
+		{0, 0},
+		// Combining acute accent
+		{0x0301, 0},
+		// Zero-width joiner and space
+		{0x200D, 0},
+		{0x200B, 0},
+		// Variation selector 16
+		{0xFE0F, 0},
+		{'日', 2},
+		{'한', 2},
+		{'ア', 2},
+		{'Ａ', 2},
+		{'😀', 2},
+		{'🚀', 2},
+		{'⌚', 2},
+	}
+
+	for _, test := range tests {
+		if width := runeWidth(test.r); width != test.expected {
+			t.Errorf("runeWidth(%U) = %d, expected %d", test.r, width, test.expected)
+		}
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		str      string
+		expected int
+	}{
+		{"", 0},
+		{"Debian", 6},
+		{"Linux 日本語", 12},
+		{"한국어", 6},
+		// Precomposed and decomposed characters
+		{"é", 1},
+		{"e\u0301", 1},
+		{"Ame\u0301lie", 6},
+		// Emoji sequences joined using zero-width joiners
+		{"👨\u200d👩\u200d👧", 2},
+		{"🏳\ufe0f\u200d🌈", 2},
+		{"a👨\u200d💻b", 4},
+		{"❤\ufe0f", 1},
+	}
+
+	for _, test := range tests {
+		if width := displayWidth(test.str); width != test.expected {
+			t.Errorf("displayWidth(%q) = %d, expected %d", test.str, width, test.expected)
+		}
+	}
+}
+
+func TestTruncateDisplayWidth(t *testing.T) {
+	tests := []struct {
+		str      string
+		width    int
+		expected string
+	}{
+		{"Debian", 6, "Debian"},
+		{"Debian", 10, "Debian"},
+		{"Debian GNU/Linux", 7, "Debian…"},
+		{"Debian", 1, "…"},
+		{"Debian", 0, ""},
+		{"\033[31mDebian\033[0m", 6, "\033[31mDebian\033[0m"},
+		{"\033[31mDebian\033[0m GNU/Linux", 4, "\033[31mDeb…"},
+		{"\033[31mDeb\033[0mian", 4, "\033[31mDeb\033[0m…"},
+		{"日本語テキスト", 6, "日本…"},
+		{"日本語テキスト", 5, "日本…"},
+	}
+
+	for _, test := range tests {
+		if truncated := truncateDisplayWidth(test.str, test.width); truncated != test.expected {
+			t.Errorf("truncateDisplayWidth(%q, %d) = %q, expected %q", test.str, test.width, truncated, test.expected)
+		}
+	}
+}